@@ -2,7 +2,7 @@ package main
 
 import (
 	"embed"
-	"fmt"
+	"log/slog"
 	"os"
 
 	"dump.link/src"
@@ -12,15 +12,8 @@ import (
 var templatesFS embed.FS
 
 func main() {
-	env := os.Getenv("ENV")
-	if env == "" {
-		env = "development"
-	}
-	fmt.Println("releaseStage:", env)
-
-	fmt.Println("Hello, world!")
 	if err := src.Run(templatesFS); err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+		slog.Error("exiting", "error", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,11 @@
+CREATE TABLE `notification_targets` (
+	`id` VARCHAR(22) NOT NULL,
+	`project_id` VARCHAR(11) NOT NULL,
+	`url` VARCHAR(2048) NOT NULL,
+	/* comma separated list of action types */
+	`actions` VARCHAR(1024) NOT NULL,
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	PRIMARY KEY (`id`),
+	CONSTRAINT `fk_notification_targets_projects` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE
+	`projects`
+ADD
+	`appetite_notified` BOOLEAN NOT NULL DEFAULT false;
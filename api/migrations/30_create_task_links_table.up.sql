@@ -0,0 +1,8 @@
+CREATE TABLE `task_links` (
+	`task_id` VARCHAR(22) NOT NULL,
+	`url` VARCHAR(2048) NOT NULL,
+	`title` VARCHAR(255) NOT NULL DEFAULT "",
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	CONSTRAINT `fk_task_links_tasks` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE
+	`projects`
+ADD
+	`github_secret` VARCHAR(64) NOT NULL DEFAULT "";
@@ -0,0 +1,15 @@
+CREATE TABLE `comments` (
+	`id` VARCHAR(22) NOT NULL,
+	`project_id` VARCHAR(11) NOT NULL,
+	`bucket_id` VARCHAR(22) NULL,
+	`task_id` VARCHAR(22) NULL,
+	`body` TEXT NOT NULL,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	INDEX `idx_comments_project` (`project_id`),
+	CONSTRAINT `fk_comments_projects` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE,
+	CONSTRAINT `fk_comments_buckets` FOREIGN KEY (`bucket_id`) REFERENCES `buckets`(`id`) ON DELETE CASCADE,
+	CONSTRAINT `fk_comments_tasks` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE
+	`tasks` DROP COLUMN `notes`;
+
+ALTER TABLE
+	`buckets` DROP COLUMN `definition_of_done`;
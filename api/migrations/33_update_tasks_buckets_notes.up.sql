@@ -0,0 +1,9 @@
+ALTER TABLE
+	`tasks`
+ADD
+	`notes` TEXT NULL;
+
+ALTER TABLE
+	`buckets`
+ADD
+	`definition_of_done` TEXT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE `task_assignees` (
+	`task_id` VARCHAR(22) NOT NULL,
+	`username` VARCHAR(255) NOT NULL,
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	PRIMARY KEY (`task_id`, `username`),
+	INDEX `idx_task_assignees_username` (`username`),
+	CONSTRAINT `fk_task_assignees_tasks` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE
+	`tasks` DROP INDEX `ft_tasks_title_notes`;
+
+ALTER TABLE
+	`buckets` DROP INDEX `ft_buckets_name_definition_of_done`;
@@ -0,0 +1,9 @@
+ALTER TABLE
+	`tasks`
+ADD
+	FULLTEXT INDEX `ft_tasks_title_notes` (`title`, `notes`);
+
+ALTER TABLE
+	`buckets`
+ADD
+	FULLTEXT INDEX `ft_buckets_name_definition_of_done` (`name`, `definition_of_done`);
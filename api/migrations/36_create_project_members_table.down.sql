@@ -0,0 +1,4 @@
+ALTER TABLE
+	`projects` DROP COLUMN `anonymous_access`;
+
+DROP TABLE IF EXISTS `project_members`;
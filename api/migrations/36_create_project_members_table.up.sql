@@ -0,0 +1,16 @@
+CREATE TABLE `project_members` (
+	`project_id` VARCHAR(11) NOT NULL,
+	/* subject of the JWT */
+	`subject` VARCHAR(255) NOT NULL,
+	`role` VARCHAR(16) NOT NULL,
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	PRIMARY KEY (`project_id`, `subject`),
+	INDEX `idx_project_members_subject` (`subject`),
+	CONSTRAINT `fk_project_members_projects` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+ALTER TABLE
+	`projects`
+ADD
+	`anonymous_access` VARCHAR(16) NOT NULL DEFAULT "editor";
@@ -0,0 +1,13 @@
+CREATE TABLE `share_tokens` (
+	`id` VARCHAR(22) NOT NULL,
+	`project_id` VARCHAR(11) NOT NULL,
+	/* sha256 of the token, the token itself is only shown once */
+	`token_hash` CHAR(64) NOT NULL,
+	`label` VARCHAR(255) NOT NULL DEFAULT "",
+	`expires_at` DATETIME NULL,
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL DEFAULT "",
+	PRIMARY KEY (`id`),
+	UNIQUE INDEX `idx_share_tokens_token_hash` (`token_hash`),
+	CONSTRAINT `fk_share_tokens_projects` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
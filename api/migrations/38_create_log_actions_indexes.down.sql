@@ -0,0 +1,5 @@
+ALTER TABLE
+	`log_actions` DROP INDEX `idx_log_actions_project_id_created_at`;
+
+ALTER TABLE
+	`log_actions` DROP INDEX `idx_log_actions_created_by_created_at`;
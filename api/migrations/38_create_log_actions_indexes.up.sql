@@ -0,0 +1,9 @@
+ALTER TABLE
+	`log_actions`
+ADD
+	INDEX `idx_log_actions_project_id_created_at` (`project_id`, `created_at`);
+
+ALTER TABLE
+	`log_actions`
+ADD
+	INDEX `idx_log_actions_created_by_created_at` (`created_by`, `created_at`);
@@ -0,0 +1,11 @@
+CREATE TABLE `broker_messages` (
+	`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	/* the API instance that published the message */
+	`instance_id` VARCHAR(32) NOT NULL,
+	`project_id` VARCHAR(11) NOT NULL,
+	`sender_token` VARCHAR(255) NOT NULL DEFAULT "",
+	`payload` MEDIUMBLOB NOT NULL,
+	`created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	INDEX `idx_broker_messages_created_at` (`created_at`)
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
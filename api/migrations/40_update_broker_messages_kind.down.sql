@@ -0,0 +1,2 @@
+ALTER TABLE
+	`broker_messages` DROP COLUMN `kind`;
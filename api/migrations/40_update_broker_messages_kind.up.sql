@@ -0,0 +1,5 @@
+ALTER TABLE
+	`broker_messages`
+ADD
+	/* empty for messages to the clients, else what the instances have to do */
+	`kind` VARCHAR(16) NOT NULL DEFAULT "";
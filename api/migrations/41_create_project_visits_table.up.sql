@@ -0,0 +1,9 @@
+CREATE TABLE `project_visits` (
+	`project_id` VARCHAR(11) NOT NULL,
+	/* subject of the JWT */
+	`subject` VARCHAR(255) NOT NULL,
+	`visited_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`project_id`, `subject`),
+	INDEX `idx_project_visits_subject_visited_at` (`subject`, `visited_at`),
+	CONSTRAINT `fk_project_visits_projects` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
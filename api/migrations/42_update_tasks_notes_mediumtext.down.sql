@@ -0,0 +1,2 @@
+ALTER TABLE
+	`tasks` MODIFY `notes` TEXT NULL;
@@ -0,0 +1,3 @@
+/* 20000 characters of utf8mb4 take up to 80000 bytes, TEXT holds 65535 */
+ALTER TABLE
+	`tasks` MODIFY `notes` MEDIUMTEXT NULL;
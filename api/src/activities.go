@@ -0,0 +1,206 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dump.link/src/models"
+)
+
+// defaultActivityTTL is how long an activity lasts without being renewed.
+const defaultActivityTTL = 30 * time.Minute
+
+// activityTracker keeps the live activities of the projects in memory, one
+// per user. The activities table only backs it, so that they survive a
+// restart. Projects are loaded from it on first use. It only knows the
+// activities set on this instance, see adr/003-websocket-broker.md.
+type activityTracker struct {
+	ttl time.Duration
+
+	mutex    sync.Mutex
+	projects map[string]map[string]*models.Activity
+}
+
+func newActivityTracker(ttl time.Duration) *activityTracker {
+	return &activityTracker{
+		ttl:      ttl,
+		projects: make(map[string]map[string]*models.Activity),
+	}
+}
+
+func (t *activityTracker) loaded(projectId string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	_, ok := t.projects[projectId]
+	return ok
+}
+
+// load fills in the stored activities of a project, leaving out expired ones.
+// Projects that were loaded in the meantime are kept as they are.
+func (t *activityTracker) load(projectId string, activities []*models.Activity, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.projects[projectId]; ok {
+		return
+	}
+
+	users := make(map[string]*models.Activity)
+	for _, activity := range activities {
+		if now.Sub(activity.CreatedAt) < t.ttl {
+			users[activity.CreatedBy] = activity
+		}
+	}
+	t.projects[projectId] = users
+}
+
+// set replaces the activity of its user.
+func (t *activityTracker) set(activity *models.Activity) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	users, ok := t.projects[activity.ProjectID]
+	if !ok {
+		users = make(map[string]*models.Activity)
+		t.projects[activity.ProjectID] = users
+	}
+	users[activity.CreatedBy] = activity
+}
+
+// remove deletes the activity of the user and reports whether there was one.
+func (t *activityTracker) remove(projectId string, username string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	users := t.projects[projectId]
+	if _, ok := users[username]; !ok {
+		return false
+	}
+
+	delete(users, username)
+	return true
+}
+
+// expire removes the activities older than the TTL and returns the users
+// they belonged to by project. Projects without activities are unloaded.
+func (t *activityTracker) expire(now time.Time) map[string][]string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	expired := make(map[string][]string)
+	for projectId, users := range t.projects {
+		for username, activity := range users {
+			if now.Sub(activity.CreatedAt) >= t.ttl {
+				delete(users, username)
+				expired[projectId] = append(expired[projectId], username)
+			}
+		}
+		if len(users) == 0 {
+			delete(t.projects, projectId)
+		}
+	}
+
+	return expired
+}
+
+// list returns the activities of the project, sorted by user.
+func (t *activityTracker) list(projectId string) []*models.Activity {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	activities := []*models.Activity{}
+	for _, activity := range t.projects[projectId] {
+		activities = append(activities, activity)
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].CreatedBy < activities[j].CreatedBy
+	})
+
+	return activities
+}
+
+// projectActivities returns the live activities of the project.
+func (app *application) projectActivities(ctx context.Context, projectId string) ([]*models.Activity, error) {
+	if !app.liveActivities.loaded(projectId) {
+		activities, err := app.activities.GetForProjectId(ctx, projectId)
+		if err != nil {
+			return nil, err
+		}
+		app.liveActivities.load(projectId, activities, time.Now())
+	}
+
+	return app.liveActivities.list(projectId), nil
+}
+
+// setActivity stores what the user works on. Without a bucket or task, the
+// user is merely active in the project.
+func (app *application) setActivity(ctx context.Context, projectId string, username string, bucketId *string, taskId *string) error {
+	// load first, so that the stored activities do not override this one.
+	_, err := app.projectActivities(ctx, projectId)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case taskId != nil:
+		err = app.activities.ReplaceTaskId(ctx, projectId, *taskId, username)
+	case bucketId != nil:
+		err = app.activities.ReplaceBucketId(ctx, projectId, *bucketId, username)
+	default:
+		err = app.activities.Reset(ctx, projectId, username)
+	}
+	if err != nil {
+		return err
+	}
+
+	app.liveActivities.set(&models.Activity{
+		ProjectID: projectId,
+		BucketID:  bucketId,
+		TaskID:    taskId,
+		CreatedBy: username,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	return nil
+}
+
+// clearActivity removes the activity of a user who left the project and
+// tells the others.
+func (app *application) clearActivity(ctx context.Context, projectId string, username string) {
+	_, err := app.projectActivities(ctx, projectId)
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error loading activities: %v", err))
+		return
+	}
+
+	if !app.liveActivities.remove(projectId, username) {
+		return
+	}
+
+	err = app.activities.Remove(ctx, projectId, username)
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error removing activity: %v", err))
+	}
+
+	app.sendActionDataToProjectClients(ctx, projectId, "", ActionUpdateActivities, app.liveActivities.list(projectId))
+}
+
+// expireActivities removes the activities nobody renewed within the TTL.
+func (app *application) expireActivities() {
+	ctx := context.Background()
+
+	for projectId, usernames := range app.liveActivities.expire(time.Now()) {
+		for _, username := range usernames {
+			err := app.activities.Remove(ctx, projectId, username)
+			if err != nil {
+				app.logger.Info(fmt.Sprintf("Error removing expired activity: %v", err))
+			}
+		}
+
+		app.sendActionDataToProjectClients(ctx, projectId, "", ActionUpdateActivities, app.liveActivities.list(projectId))
+	}
+}
@@ -0,0 +1,63 @@
+package src
+
+import (
+	"testing"
+	"time"
+
+	"dump.link/src/models"
+)
+
+func TestActivityTracker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newActivityTracker(30 * time.Minute)
+
+	tracker.load("abc", []*models.Activity{
+		{ProjectID: "abc", CreatedBy: "Grace", CreatedAt: now.Add(-time.Minute)},
+		{ProjectID: "abc", CreatedBy: "Ghost", CreatedAt: now.Add(-time.Hour)},
+	}, now)
+
+	if !tracker.loaded("abc") || tracker.loaded("xyz") {
+		t.Fatal("loaded() does not match the loaded projects")
+	}
+	if got := usernamesOf(tracker.list("abc")); got != "Grace" {
+		t.Errorf("list() after load = %s, want the unexpired Grace", got)
+	}
+
+	// loading again keeps what is in memory.
+	tracker.set(&models.Activity{ProjectID: "abc", CreatedBy: "Ada", CreatedAt: now})
+	tracker.load("abc", nil, now)
+	if got := usernamesOf(tracker.list("abc")); got != "Ada,Grace" {
+		t.Errorf("list() = %s, want Ada,Grace", got)
+	}
+
+	if !tracker.remove("abc", "Grace") {
+		t.Error("remove(Grace) = false, want true")
+	}
+	if tracker.remove("abc", "Grace") {
+		t.Error("remove(Grace) twice = true, want false")
+	}
+
+	expired := tracker.expire(now.Add(29 * time.Minute))
+	if len(expired) != 0 {
+		t.Errorf("expire() before the TTL = %v, want nothing", expired)
+	}
+
+	expired = tracker.expire(now.Add(30 * time.Minute))
+	if got := expired["abc"]; len(got) != 1 || got[0] != "Ada" {
+		t.Errorf("expire() = %v, want Ada in abc", expired)
+	}
+	if tracker.loaded("abc") {
+		t.Error("project without activities is still loaded")
+	}
+}
+
+func usernamesOf(activities []*models.Activity) string {
+	usernames := ""
+	for i, activity := range activities {
+		if i > 0 {
+			usernames += ","
+		}
+		usernames += activity.CreatedBy
+	}
+	return usernames
+}
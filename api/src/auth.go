@@ -0,0 +1,116 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	"gopkg.in/go-jose/go-jose.v2/jwt"
+)
+
+// authenticator holds the JWT middlewares. They are built once at startup and
+// share the verifier and with it the JWKS cache.
+type authenticator struct {
+	required *jwtmiddleware.JWTMiddleware
+	optional *jwtmiddleware.JWTMiddleware
+}
+
+// newAuthenticator fails on an invalid OIDC configuration, so that the server
+// does not start instead of failing on the first authenticated request.
+func (app *application) newAuthenticator(cfg oidcConfig) (*authenticator, error) {
+	verifier, err := newTokenVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// an unreachable provider may recover, so only warn about it.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = verifier.fetchKeys(ctx)
+	if err != nil {
+		app.logger.Warn("could not fetch the JWKS", "issuer", cfg.Issuer, "error", err.Error())
+	}
+
+	newMiddleware := func(credentialsOptional bool) *jwtmiddleware.JWTMiddleware {
+		return jwtmiddleware.New(
+			verifier.ValidateToken,
+			jwtmiddleware.WithCredentialsOptional(credentialsOptional),
+			// browsers can not set headers on websocket connections.
+			jwtmiddleware.WithTokenExtractor(jwtmiddleware.MultiTokenExtractor(
+				jwtmiddleware.AuthHeaderTokenExtractor,
+				jwtmiddleware.ParameterTokenExtractor("access_token"),
+			)),
+			jwtmiddleware.WithErrorHandler(app.invalidTokenResponse),
+		)
+	}
+
+	return &authenticator{
+		required: newMiddleware(false),
+		optional: newMiddleware(true),
+	}, nil
+}
+
+// EnsureValidToken is a middleware that checks the validity of our JWT.
+func (app *application) EnsureValidToken(next http.HandlerFunc) http.HandlerFunc {
+	handler := app.auth.required.CheckJWT(next)
+	return handler.ServeHTTP
+}
+
+// OptionalValidToken checks the JWT like EnsureValidToken, but lets requests
+// without any token pass. Invalid tokens are still rejected.
+func (app *application) OptionalValidToken(next http.HandlerFunc) http.HandlerFunc {
+	handler := app.auth.optional.CheckJWT(next)
+	return handler.ServeHTTP
+}
+
+// invalidTokenResponse tells clients why their token was rejected, so that
+// they can tell an expired session from a misconfigured one.
+func (app *application) invalidTokenResponse(w http.ResponseWriter, r *http.Request, err error) {
+	code, message := "invalid_token", "the access token is invalid"
+	switch {
+	case errors.Is(err, jwtmiddleware.ErrJWTMissing):
+		code, message = "missing_token", "an access token is required"
+	case errors.Is(err, jwt.ErrExpired):
+		code, message = "expired_token", "the access token has expired"
+	case !errors.Is(err, jwtmiddleware.ErrJWTInvalid):
+		// the token could not be extracted, e.g. a malformed header.
+		code, message = "malformed_token", err.Error()
+	}
+
+	app.logger.Debug("rejected JWT", "code", code, "error", err.Error(), "uri", r.URL.Path)
+
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	app.errorResponse(w, r, http.StatusUnauthorized, envelope{
+		"code":    code,
+		"message": message,
+	})
+}
+
+// subjectFromRequest returns the username of the validated JWT, as mapped by
+// OIDC_USERNAME_CLAIM, or an empty string for anonymous requests.
+func subjectFromRequest(r *http.Request) string {
+	claims, ok := r.Context().Value(jwtmiddleware.ContextKey{}).(*validator.ValidatedClaims)
+	if !ok {
+		return ""
+	}
+	if custom, ok := claims.CustomClaims.(*tokenClaims); ok {
+		return custom.Username
+	}
+	return claims.RegisteredClaims.Subject
+}
+
+// verifiedEmailFromRequest returns the email address of the validated JWT, if
+// the provider verified it.
+func verifiedEmailFromRequest(r *http.Request) string {
+	claims, ok := r.Context().Value(jwtmiddleware.ContextKey{}).(*validator.ValidatedClaims)
+	if !ok {
+		return ""
+	}
+	if custom, ok := claims.CustomClaims.(*tokenClaims); ok {
+		return custom.Email
+	}
+	return ""
+}
@@ -1,77 +0,0 @@
-package src
-
-import (
-	"context"
-	"log"
-	"net/http"
-	"net/url"
-	"os"
-	"strings"
-	"time"
-
-	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
-	"github.com/auth0/go-jwt-middleware/v2/jwks"
-	"github.com/auth0/go-jwt-middleware/v2/validator"
-)
-
-// CustomClaims contains custom data we want from the token.
-type CustomClaims struct {
-	Scope string `json:"scope"`
-}
-
-// Validate does nothing for this example, but we need
-// it to satisfy validator.CustomClaims interface.
-func (c CustomClaims) Validate(ctx context.Context) error {
-	return nil
-} // EnsureValidToken is a middleware that checks the validity of our JWT.
-func EnsureValidToken(next func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		issuerURL, err := url.Parse("https://" + os.Getenv("AUTH0_DOMAIN") + "/")
-		if err != nil {
-			log.Fatalf("Failed to parse the issuer URL: %v", err)
-		}
-
-		provider := jwks.NewCachingProvider(issuerURL, 5*time.Minute)
-
-		jwtValidator, err := validator.New(
-			provider.KeyFunc,
-			validator.RS256,
-			issuerURL.String(),
-			[]string{os.Getenv("AUTH0_AUDIENCE")},
-			validator.WithCustomClaims(
-				func() validator.CustomClaims {
-					return &CustomClaims{}
-				},
-			),
-			validator.WithAllowedClockSkew(time.Minute),
-		)
-		if err != nil {
-			log.Fatalf("Failed to set up the JWT validator")
-		}
-
-		middleware := jwtmiddleware.New(
-			jwtValidator.ValidateToken,
-			jwtmiddleware.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
-				log.Printf("Encountered error while validating JWT: %v", err)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte(`{"message":"Failed to validate JWT."}`))
-			}),
-		)
-
-		nextHandler := http.HandlerFunc(next)
-		middleware.CheckJWT(nextHandler).ServeHTTP(w, r)
-	}
-}
-
-// HasScope checks whether our claims have a specific scope.
-func (c CustomClaims) HasScope(expectedScope string) bool {
-	result := strings.Split(c.Scope, " ")
-	for i := range result {
-		if result[i] == expectedScope {
-			return true
-		}
-	}
-
-	return false
-}
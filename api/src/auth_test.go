@@ -0,0 +1,88 @@
+package src
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+)
+
+func TestAuthenticator(t *testing.T) {
+	verifier, sign := newTestVerifier(t, oidcConfig{
+		Issuer:        testIssuer,
+		Audience:      []string{"dump.link"},
+		Algorithms:    []validator.SignatureAlgorithm{validator.RS256},
+		UsernameClaim: "sub",
+		ScopeClaim:    "scope",
+	})
+
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	var err error
+	app.auth, err = app.newAuthenticator(verifier.config)
+	if err != nil {
+		t.Fatalf("newAuthenticator() error = %v", err)
+	}
+
+	handler := app.EnsureValidToken(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(subjectFromRequest(r)))
+	})
+
+	valid := sign(map[string]any{"iss": testIssuer, "aud": "dump.link", "sub": "auth0|123", "exp": time.Now().Add(time.Hour).Unix()})
+	expired := sign(map[string]any{"iss": testIssuer, "aud": "dump.link", "sub": "auth0|123", "exp": time.Now().Add(-time.Hour).Unix()})
+
+	tests := []struct {
+		name       string
+		header     string
+		query      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"Header", "Bearer " + valid, "", http.StatusOK, ""},
+		{"Query", "", "?access_token=" + valid, http.StatusOK, ""},
+		{"Missing", "", "", http.StatusUnauthorized, "missing_token"},
+		{"Expired", "Bearer " + expired, "", http.StatusUnauthorized, "expired_token"},
+		{"Garbage", "Bearer nope", "", http.StatusUnauthorized, "invalid_token"},
+		{"Malformed header", "Basic abc", "", http.StatusUnauthorized, "malformed_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/private"+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != "auth0|123" {
+					t.Errorf("subject = %q, want %q", w.Body.String(), "auth0|123")
+				}
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package src
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"dump.link/src/models"
+)
+
+// requireProjectRole only lets requests pass whose JWT subject has at least
+// the given role in the project. Everybody else, including anonymous
+// requests, gets the project's anonymous access. On shareable routes, a valid
+// share token makes anybody a viewer. The others ignore share tokens, so that
+// a link only opens the board and not, e.g., the members or the comments. The
+// granted role is stored in the request context, like the share token if it
+// is what let the request in.
+func (app *application) requireProjectRole(role string, shareable bool, next http.HandlerFunc) http.HandlerFunc {
+	return app.OptionalValidToken(func(w http.ResponseWriter, r *http.Request) {
+		projectId, valid := app.getAndValidateID(w, r, "projectId")
+		if !valid {
+			return
+		}
+
+		project, err := app.projects.Get(r.Context(), projectId)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		subject := subjectFromRequest(r)
+		granted, err := app.projectRole(r.Context(), project, subject)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if token := shareTokenFromRequest(r); shareable && token != "" {
+			share, err := app.projectShareToken(r.Context(), projectId, token)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if share == nil {
+				app.invalidShareTokenResponse(w, r)
+				return
+			}
+			if models.RoleRank(granted) < models.RoleRank(models.RoleViewer) {
+				granted = models.RoleViewer
+				r = app.contextSetShareTokenID(r, share.ID)
+			}
+		}
+
+		if models.RoleRank(granted) < models.RoleRank(role) {
+			if subject == "" && models.RoleRank(granted) == 0 {
+				app.authenticationRequiredResponse(w, r)
+				return
+			}
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		r = app.contextSetProjectRole(r, granted)
+		next(w, r)
+	})
+}
+
+// projectRole returns the better of the subject's membership role and the
+// project's anonymous access.
+func (app *application) projectRole(ctx context.Context, project *models.Project, subject string) (string, error) {
+	role := project.AnonymousAccess
+
+	if subject != "" {
+		memberRole, err := app.members.GetRole(ctx, project.ID, subject)
+		if err != nil {
+			return "", err
+		}
+		if models.RoleRank(memberRole) > models.RoleRank(role) {
+			role = memberRole
+		}
+	}
+
+	return role, nil
+}
+
+// canViewProject reports whether the subject may read the project.
+func (app *application) canViewProject(ctx context.Context, projectId string, subject string) (bool, error) {
+	project, err := app.projects.Get(ctx, projectId)
+	if err != nil {
+		return false, err
+	}
+
+	role, err := app.projectRole(ctx, project, subject)
+	if err != nil {
+		return false, err
+	}
+
+	return models.RoleRank(role) >= models.RoleRank(models.RoleViewer), nil
+}
+
+// shareTokenFromRequest reads the share token from the Share-Token header or,
+// for page loads and websockets, the share query parameter.
+func shareTokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get("Share-Token"); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("share")
+}
+
+// projectShareToken returns the share token if it exists, is not expired and
+// belongs to the project, nil otherwise.
+func (app *application) projectShareToken(ctx context.Context, projectId string, token string) (*models.ShareToken, error) {
+	share, err := app.shareTokens.GetByToken(ctx, token)
+	if err != nil || share == nil {
+		return nil, err
+	}
+
+	if share.ProjectID != projectId || share.Expired(time.Now()) {
+		return nil, nil
+	}
+	return share, nil
+}
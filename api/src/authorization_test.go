@@ -0,0 +1,55 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRequireProjectRoleShareLink(t *testing.T) {
+	shareId := testProjectID + "Sh4Re6Tk8Nm"
+
+	tests := []struct {
+		name      string
+		shareable bool
+		want      int
+	}{
+		{"SharedRoute", true, http.StatusOK},
+		// e.g. the members, whose subjects the link must not reveal.
+		{"OtherRoute", false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			expectExists(mock, "projects", true)
+			expectProject(mock, testProjectID, models.RoleNone)
+			if tt.shareable {
+				mock.ExpectQuery(`SELECT id, project_id, .* FROM share_tokens WHERE token_hash = \?`).
+					WithArgs(models.ToSHA256Hash("s3cret")).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "label", "expires_at", "created_at", "created_by"}).
+						AddRow(shareId, testProjectID, "Stakeholders", nil, testCreatedAt, "ada-sub"))
+			}
+
+			var role, granted string
+			next := func(w http.ResponseWriter, r *http.Request) {
+				role = app.contextGetProjectRole(r)
+				granted = shareTokenIDFromContext(r.Context())
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/projects/"+testProjectID+"?share=s3cret", nil)
+			w := serveRoute("/api/v1/projects/:projectId", app.requireProjectRole(models.RoleViewer, tt.shareable, next), r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK && (role != models.RoleViewer || granted != shareId) {
+				t.Errorf("role = %q, share token = %q, want a viewer let in by %s", role, granted, shareId)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"dump.link/src/models"
+)
+
+const (
+	// brokerPollBatch caps the messages fetched per query.
+	brokerPollBatch = 500
+	// brokerRetention is how long published messages are kept. Instances
+	// that fall further behind miss them.
+	brokerRetention = 5 * time.Minute
+	// brokerReplayWindow is how many IDs below the newest message are read
+	// again on every poll. Inserts may commit in another order than their
+	// IDs were given out, a message whose insert commits later than this
+	// many newer ones is missed.
+	brokerReplayWindow = 200
+)
+
+// brokerEvictShare asks every instance to close the clients that joined the
+// project with the share token whose ID is the payload.
+const brokerEvictShare = "evictShare"
+
+// brokerMessage is a websocket message for the clients of a project, on
+// whichever instance they are connected. Messages with a Kind are for the
+// instances instead.
+type brokerMessage struct {
+	Kind        string
+	ProjectID   string
+	SenderToken string
+	Payload     []byte
+}
+
+// broker fans websocket messages out to every API instance. Each instance
+// hands the messages to its own hub.
+type broker interface {
+	Publish(ctx context.Context, message brokerMessage) error
+}
+
+// brokerConfig selects the broker with BROKER, "memory" for a single
+// instance or "mysql" to share the messages through the database.
+type brokerConfig struct {
+	Kind         string
+	PollInterval time.Duration
+}
+
+func brokerConfigFromEnv(getenv func(string) string) (brokerConfig, error) {
+	cfg := brokerConfig{
+		Kind:         "memory",
+		PollInterval: 250 * time.Millisecond,
+	}
+
+	if kind := getenv("BROKER"); kind != "" {
+		cfg.Kind = kind
+	}
+	if cfg.Kind != "memory" && cfg.Kind != "mysql" {
+		return cfg, fmt.Errorf("BROKER: unknown broker %q, expected memory or mysql", cfg.Kind)
+	}
+
+	if interval := getenv("BROKER_POLL_INTERVAL"); interval != "" {
+		var err error
+		cfg.PollInterval, err = time.ParseDuration(interval)
+		if err != nil || cfg.PollInterval <= 0 {
+			return cfg, fmt.Errorf("BROKER_POLL_INTERVAL: invalid duration %q", interval)
+		}
+	}
+
+	return cfg, nil
+}
+
+// newBroker sets up the configured broker, delivering to the hub.
+func (app *application) newBroker(cfg brokerConfig) (broker, error) {
+	if cfg.Kind == "memory" {
+		return &memoryBroker{deliver: app.deliver}, nil
+	}
+
+	b, err := newPollingBroker(app.brokerMessages, app.deliver, app.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	app.schedule("broker poll", cfg.PollInterval, b.poll)
+	app.schedule("broker cleanup", time.Minute, b.cleanup)
+
+	return b, nil
+}
+
+// deliver hands a message of the broker to the hub.
+func (app *application) deliver(ctx context.Context, message brokerMessage) {
+	switch message.Kind {
+	case brokerEvictShare:
+		app.hub.evictShareToken(message.ProjectID, string(message.Payload))
+	default:
+		app.hub.broadcast(ctx, message.ProjectID, message.SenderToken, message.Payload)
+	}
+}
+
+// memoryBroker delivers to the local hub only.
+type memoryBroker struct {
+	deliver func(context.Context, brokerMessage)
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, message brokerMessage) error {
+	b.deliver(ctx, message)
+	return nil
+}
+
+// brokerStore is where the polling broker exchanges messages with the other
+// instances.
+type brokerStore interface {
+	Insert(ctx context.Context, message *models.BrokerMessage) error
+	LatestID(ctx context.Context) (int64, error)
+	GetAfter(ctx context.Context, id int64, instanceID string, limit int) ([]*models.BrokerMessage, error)
+	DeleteOlderThan(ctx context.Context, age time.Duration) error
+}
+
+// pollingBroker shares the messages through a table every instance polls. It
+// delivers its own messages right away, the ones of other instances on the
+// next poll.
+type pollingBroker struct {
+	store      brokerStore
+	instanceID string
+	deliver    func(context.Context, brokerMessage)
+	logger     *slog.Logger
+
+	mutex sync.Mutex
+	// startID is the newest message when the instance started, lastID the
+	// newest one delivered since.
+	startID int64
+	lastID  int64
+	// delivered are the IDs of the replay window that were delivered
+	// already.
+	delivered map[int64]bool
+}
+
+// newPollingBroker starts after the newest message, so a restarted instance
+// does not replay old messages to its clients.
+func newPollingBroker(store brokerStore, deliver func(context.Context, brokerMessage), logger *slog.Logger) (*pollingBroker, error) {
+	instanceID, err := models.NewSecret(16)
+	if err != nil {
+		return nil, err
+	}
+
+	lastID, err := store.LatestID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("broker: %w", err)
+	}
+
+	return &pollingBroker{
+		store:      store,
+		instanceID: instanceID,
+		deliver:    deliver,
+		logger:     logger,
+		startID:    lastID,
+		lastID:     lastID,
+		delivered:  make(map[int64]bool),
+	}, nil
+}
+
+func (b *pollingBroker) Publish(ctx context.Context, message brokerMessage) error {
+	b.deliver(ctx, message)
+
+	return b.store.Insert(ctx, &models.BrokerMessage{
+		InstanceID:  b.instanceID,
+		Kind:        message.Kind,
+		ProjectID:   message.ProjectID,
+		SenderToken: message.SenderToken,
+		Payload:     message.Payload,
+	})
+}
+
+// poll delivers the messages other instances published since the last poll.
+// The replay window below the newest message is read again, for the messages
+// that were not committed yet on the last poll.
+func (b *pollingBroker) poll() {
+	ctx := context.Background()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	after := max(b.lastID-brokerReplayWindow, b.startID)
+	for {
+		messages, err := b.store.GetAfter(ctx, after, b.instanceID, brokerPollBatch)
+		if err != nil {
+			b.logger.Error("polling broker messages", "error", err)
+			return
+		}
+
+		for _, message := range messages {
+			after = message.ID
+			if b.delivered[message.ID] {
+				continue
+			}
+			b.delivered[message.ID] = true
+			b.lastID = max(b.lastID, message.ID)
+			b.deliver(ctx, brokerMessage{
+				Kind:        message.Kind,
+				ProjectID:   message.ProjectID,
+				SenderToken: message.SenderToken,
+				Payload:     message.Payload,
+			})
+		}
+
+		if len(messages) < brokerPollBatch {
+			break
+		}
+	}
+
+	for id := range b.delivered {
+		if id <= b.lastID-brokerReplayWindow {
+			delete(b.delivered, id)
+		}
+	}
+}
+
+func (b *pollingBroker) cleanup() {
+	err := b.store.DeleteOlderThan(context.Background(), brokerRetention)
+	if err != nil {
+		b.logger.Error("removing old broker messages", "error", err)
+	}
+}
@@ -0,0 +1,180 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"dump.link/src/models"
+)
+
+// memoryBrokerStore stands in for the broker_messages table.
+type memoryBrokerStore struct {
+	mutex    sync.Mutex
+	messages []*models.BrokerMessage
+	// pending are the IDs of the inserts that did not commit yet.
+	pending map[int64]bool
+}
+
+func (s *memoryBrokerStore) Insert(ctx context.Context, message *models.BrokerMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message.ID = int64(len(s.messages) + 1)
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *memoryBrokerStore) LatestID(ctx context.Context) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return int64(len(s.messages)), nil
+}
+
+func (s *memoryBrokerStore) GetAfter(ctx context.Context, id int64, instanceID string, limit int) ([]*models.BrokerMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	messages := []*models.BrokerMessage{}
+	for _, message := range s.messages {
+		if message.ID > id && message.InstanceID != instanceID && !s.pending[message.ID] && len(messages) < limit {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (s *memoryBrokerStore) DeleteOlderThan(ctx context.Context, age time.Duration) error {
+	return nil
+}
+
+func TestPollingBroker(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memoryBrokerStore{}
+
+	// a message from before the instances started is not replayed.
+	store.Insert(context.Background(), &models.BrokerMessage{InstanceID: "old", ProjectID: "abc", Payload: []byte("old")})
+
+	var received [2][]brokerMessage
+	newInstance := func(i int) *pollingBroker {
+		b, err := newPollingBroker(store, func(ctx context.Context, message brokerMessage) {
+			received[i] = append(received[i], message)
+		}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	first, second := newInstance(0), newInstance(1)
+
+	err := first.Publish(context.Background(), brokerMessage{ProjectID: "abc", SenderToken: "t1", Payload: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received[0]) != 1 {
+		t.Fatalf("publishing instance got %d messages, want 1", len(received[0]))
+	}
+	if len(received[1]) != 0 {
+		t.Fatalf("other instance got %d messages before polling, want 0", len(received[1]))
+	}
+
+	first.poll()
+	second.poll()
+
+	if len(received[0]) != 1 {
+		t.Errorf("publishing instance got its own message again")
+	}
+	if len(received[1]) != 1 {
+		t.Fatalf("other instance got %d messages, want 1", len(received[1]))
+	}
+	got := received[1][0]
+	if got.ProjectID != "abc" || got.SenderToken != "t1" || string(got.Payload) != "hello" {
+		t.Errorf("other instance got %+v", got)
+	}
+
+	second.poll()
+	if len(received[1]) != 1 {
+		t.Errorf("polling again delivered the message twice")
+	}
+
+	// messages for the instances keep their kind.
+	first.Publish(context.Background(), brokerMessage{Kind: brokerEvictShare, ProjectID: "abc", Payload: []byte("s1")})
+	second.poll()
+	if len(received[1]) != 2 || received[1][1].Kind != brokerEvictShare {
+		t.Errorf("other instance got %+v, want the eviction", received[1])
+	}
+}
+
+func TestPollingBrokerOutOfOrderCommit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memoryBrokerStore{pending: make(map[int64]bool)}
+
+	var received []string
+	b, err := newPollingBroker(store, func(ctx context.Context, message brokerMessage) {
+		received = append(received, string(message.Payload))
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first insert commits after the second one was polled.
+	store.Insert(context.Background(), &models.BrokerMessage{InstanceID: "other", ProjectID: "abc", Payload: []byte("slow")})
+	store.pending[1] = true
+	store.Insert(context.Background(), &models.BrokerMessage{InstanceID: "other", ProjectID: "abc", Payload: []byte("fast")})
+
+	b.poll()
+	if !reflect.DeepEqual(received, []string{"fast"}) {
+		t.Fatalf("received = %v, want [fast]", received)
+	}
+
+	delete(store.pending, 1)
+	b.poll()
+	b.poll()
+	if !reflect.DeepEqual(received, []string{"fast", "slow"}) {
+		t.Errorf("received = %v, want [fast slow] without duplicates", received)
+	}
+
+	// messages beyond the window are forgotten again.
+	for i := 0; i < brokerReplayWindow; i++ {
+		store.Insert(context.Background(), &models.BrokerMessage{InstanceID: "other", ProjectID: "abc", Payload: []byte("more")})
+	}
+	b.poll()
+	if len(b.delivered) > brokerReplayWindow {
+		t.Errorf("remembers %d delivered IDs, want at most %d", len(b.delivered), brokerReplayWindow)
+	}
+}
+
+func TestBrokerConfigFromEnv(t *testing.T) {
+	t.Setenv("BROKER", "")
+	t.Setenv("BROKER_POLL_INTERVAL", "")
+
+	cfg, err := brokerConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Kind != "memory" {
+		t.Errorf("default broker = %q, want memory", cfg.Kind)
+	}
+
+	t.Setenv("BROKER", "mysql")
+	t.Setenv("BROKER_POLL_INTERVAL", "1s")
+	cfg, err = brokerConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Kind != "mysql" || cfg.PollInterval != time.Second {
+		t.Errorf("brokerConfigFromEnv(os.Getenv) = %+v", cfg)
+	}
+
+	t.Setenv("BROKER", "carrier-pigeon")
+	if _, err := brokerConfigFromEnv(os.Getenv); err == nil {
+		t.Error("expected an error for an unknown broker")
+	}
+}
@@ -0,0 +1,231 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"dump.link/src/models"
+	"github.com/julienschmidt/httprouter"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// wsCommand is a mutation a client sends over its websocket instead of a REST
+// request. The ID is chosen by the client and returned in the ack. Params
+// holds the IDs of the REST path besides the project, e.g. taskId.
+type wsCommand struct {
+	ID      string            `json:"id"`
+	Command ActionType        `json:"command"`
+	Params  map[string]string `json:"params"`
+	Data    json.RawMessage   `json:"data"`
+}
+
+// wsAck answers a command with the status and body of the REST response.
+type wsAck struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type wsCommandRoute struct {
+	method  string
+	path    string
+	handler func(*application, http.ResponseWriter, *http.Request)
+}
+
+// wsCommands maps the commands to the handlers of their REST routes, so they
+// are validated, persisted and broadcast the same way. All of them need the
+// editor role at the time they are sent.
+var wsCommands = map[ActionType]wsCommandRoute{
+	ActionUpdateProject:          {http.MethodPatch, "/api/v1/projects/:projectId", (*application).ApiProjectPatch},
+	ActionResetProjectLayers:     {http.MethodPost, "/api/v1/projects/:projectId/resetLayers", (*application).ApiResetProjectLayers},
+	ActionUpdateActivities:       {http.MethodPost, "/api/v1/projects/:projectId/activities", (*application).ApiActivityPost},
+	ActionAddTask:                {http.MethodPost, "/api/v1/projects/:projectId/tasks", (*application).ApiPostTask},
+	ActionUpdateTask:             {http.MethodPatch, "/api/v1/projects/:projectId/tasks/:taskId", (*application).ApiPatchTask},
+	ActionDeleteTask:             {http.MethodDelete, "/api/v1/projects/:projectId/tasks/:taskId", (*application).ApiDeleteTask},
+	ActionUpdateBucket:           {http.MethodPatch, "/api/v1/projects/:projectId/buckets/:bucketId", (*application).ApiPatchBucket},
+	ActionResetBucketLayers:      {http.MethodPost, "/api/v1/projects/:projectId/buckets/:bucketId/resetLayers", (*application).ApiResetBucketLayers},
+	ActionAddBucketDependency:    {http.MethodPost, "/api/v1/projects/:projectId/dependencies", (*application).ApiAddDependency},
+	ActionRemoveBucketDependency: {http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", (*application).ApiRemoveDependency},
+	ActionAddComment:             {http.MethodPost, "/api/v1/projects/:projectId/comments", (*application).ApiPostComment},
+	ActionUpdateComment:          {http.MethodPatch, "/api/v1/projects/:projectId/comments/:commentId", (*application).ApiPatchComment},
+	ActionDeleteComment:          {http.MethodDelete, "/api/v1/projects/:projectId/comments/:commentId", (*application).ApiDeleteComment},
+}
+
+// commandRecorder collects the response of a command's handler.
+type commandRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newCommandRecorder() *commandRecorder {
+	return &commandRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (rec *commandRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *commandRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *commandRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+// handleCommand runs a command of the client and sends it the ack. The ctx is
+// the one of the websocket request, carrying the client's token claims. Other
+// clients only learn about the change through the handler's broadcast, which
+// skips the sender.
+func (app *application) handleCommand(ctx context.Context, client *wsClient, message []byte) {
+	// every command is a trace of its own, linked to the one of the
+	// websocket, which lasts as long as the connection.
+	ctx, span := tracer.Start(ctx, "websocket command",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(projectAttr(client.projectId)),
+	)
+	defer span.End()
+	ctx = context.WithValue(ctx, requestIDContextKey, models.NewID())
+
+	var cmd wsCommand
+	rec := newCommandRecorder()
+
+	r, err := app.commandRequest(ctx, client, &cmd, message)
+	if cmd.Command != "" {
+		span.SetName("websocket command " + string(cmd.Command))
+	}
+	switch {
+	case err != nil:
+		app.badRequestResponse(rec, r, err)
+	default:
+		app.runCommand(rec, r, client, wsCommands[cmd.Command])
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+
+	data := json.RawMessage(bytes.TrimSpace(rec.body.Bytes()))
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+
+	ack := wsEnvelope{
+		Action: ActionAck,
+		Data:   wsAck{ID: cmd.ID, Status: rec.status, Data: data},
+	}
+	messageJSON, err := json.Marshal(ack)
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error marshalling WebSocket ack: %v", err))
+		return
+	}
+
+	app.hub.send(client, messageJSON)
+}
+
+// commandRequest decodes the command and turns it into the request of its
+// REST route. On errors, it still returns a request to respond to.
+func (app *application) commandRequest(ctx context.Context, client *wsClient, cmd *wsCommand, message []byte) (*http.Request, error) {
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/ws/"+client.projectId, nil)
+
+	dec := json.NewDecoder(bytes.NewReader(message))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(cmd)
+	if err != nil {
+		return r, fmt.Errorf("command contains badly-formed JSON: %v", err)
+	}
+	if strings.TrimSpace(cmd.ID) == "" {
+		return r, errors.New("command must have an id")
+	}
+
+	route, ok := wsCommands[cmd.Command]
+	if !ok {
+		return r, fmt.Errorf("unknown command %q", cmd.Command)
+	}
+
+	// the project is always the one of the websocket.
+	params := httprouter.Params{}
+	segments := strings.Split(route.path, "/")
+	for i, segment := range segments {
+		key, found := strings.CutPrefix(segment, ":")
+		if !found {
+			continue
+		}
+
+		value := client.projectId
+		if key != "projectId" {
+			value = cmd.Params[key]
+		}
+		if value == "" {
+			return r, fmt.Errorf("params.%s is required", key)
+		}
+
+		params = append(params, httprouter.Param{Key: key, Value: value})
+		segments[i] = url.PathEscape(value)
+	}
+
+	var body io.Reader
+	if len(cmd.Data) > 0 {
+		body = bytes.NewReader(cmd.Data)
+	}
+
+	target := strings.Join(segments, "/") + "?token=" + url.QueryEscape(client.clientToken)
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, params)
+	r, err = http.NewRequestWithContext(ctx, route.method, target, body)
+	if err != nil {
+		return r, err
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Username", url.QueryEscape(client.clientUsername))
+
+	return r, nil
+}
+
+// runCommand checks the role and the mutation rate limit of the client and
+// runs the handler, recovering from its panics like the REST middleware does.
+func (app *application) runCommand(w http.ResponseWriter, r *http.Request, client *wsClient, route wsCommandRoute) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
+		}
+	}()
+
+	// members and the anonymous access may have changed since the client
+	// connected, so the role is resolved for every command.
+	project, err := app.projects.Get(r.Context(), client.projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	role, err := app.projectRole(r.Context(), project, subjectFromRequest(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if models.RoleRank(role) < models.RoleRank(models.RoleEditor) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+	r = app.contextSetProjectRole(r, role)
+
+	if app.limiter != nil && app.limiter.config.Enabled {
+		allowed, retryAfter := app.limiter.allow(routeClassMutation, client.ip, client.projectId, time.Now())
+		if !allowed {
+			app.logger.Info("rate limit exceeded", "ip", client.ip, "class", routeClassMutation, "projectId", client.projectId)
+			app.rateLimitExceededResponse(w, r, retryAfter)
+			return
+		}
+	}
+
+	route.handler(app, w, r)
+}
@@ -0,0 +1,174 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/julienschmidt/httprouter"
+)
+
+const actionTestCommand ActionType = "TEST_COMMAND"
+
+func TestHandleCommand(t *testing.T) {
+	wsCommands[actionTestCommand] = wsCommandRoute{http.MethodPatch, "/api/v1/projects/:projectId/tasks/:taskId", func(app *application, w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+		username, _ := app.getUsernameFromHeader(r)
+
+		var input struct {
+			Title string `json:"title"`
+		}
+		if err := app.readJSON(w, r, &input); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		app.writeJSON(w, http.StatusOK, envelope{
+			"projectId": params.ByName("projectId"),
+			"taskId":    params.ByName("taskId"),
+			"username":  username,
+			"token":     app.getTokenFromRequest(r),
+			"title":     input.Title,
+		}, nil)
+	}}
+	defer delete(wsCommands, actionTestCommand)
+
+	tests := []struct {
+		name string
+		// role is the anonymous access of the project when the command is
+		// sent, whatever it was when the client connected. Commands that
+		// can not be read never get to it.
+		role    string
+		message string
+		status  int
+		data    map[string]any
+	}{
+		{
+			name:    "runs the handler",
+			role:    models.RoleEditor,
+			message: `{"id":"c1","command":"TEST_COMMAND","params":{"taskId":"abcdefghijkmnopqrstuvw"},"data":{"title":"Ship it"}}`,
+			status:  http.StatusOK,
+			data: map[string]any{
+				"projectId": "abcdefghijk",
+				"taskId":    "abcdefghijkmnopqrstuvw",
+				"username":  "Ada Lovelace",
+				"token":     "t1",
+				"title":     "Ship it",
+			},
+		},
+		{
+			name:    "viewers may not send commands",
+			role:    models.RoleViewer,
+			message: `{"id":"c1","command":"TEST_COMMAND","params":{"taskId":"abcdefghijkmnopqrstuvw"},"data":{}}`,
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "unknown command",
+			message: `{"id":"c1","command":"UPDATE_EVERYTHING"}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "missing param",
+			message: `{"id":"c1","command":"TEST_COMMAND","data":{}}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "missing id",
+			message: `{"command":"TEST_COMMAND","params":{"taskId":"abcdefghijkmnopqrstuvw"},"data":{}}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "raw events are rejected",
+			message: `{"action":"UPDATE_TASK","data":{"id":"abcdefghijkmnopqrstuvw","title":"fake"}}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "unknown fields in data",
+			role:    models.RoleEditor,
+			message: `{"id":"c1","command":"TEST_COMMAND","params":{"taskId":"abcdefghijkmnopqrstuvw"},"data":{"evil":true}}`,
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			client := newTestClient("abcdefghijk", "t1", "Ada Lovelace", 1)
+			app.hub.register(client)
+			defer app.hub.unregister(client)
+
+			if tt.role != "" {
+				expectProject(mock, "abcdefghijk", tt.role)
+			}
+
+			app.handleCommand(context.Background(), client, []byte(tt.message))
+
+			var ack struct {
+				Action ActionType `json:"action"`
+				Data   struct {
+					ID     string         `json:"id"`
+					Status int            `json:"status"`
+					Data   map[string]any `json:"data"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal((<-client.send).payload, &ack); err != nil {
+				t.Fatal(err)
+			}
+
+			if ack.Action != ActionAck {
+				t.Errorf("action = %q, want %q", ack.Action, ActionAck)
+			}
+			if ack.Data.Status != tt.status {
+				t.Errorf("status = %d, want %d (%v)", ack.Data.Status, tt.status, ack.Data.Data)
+			}
+			if tt.status >= http.StatusBadRequest {
+				if _, ok := ack.Data.Data["error"]; !ok {
+					t.Errorf("data = %v, want an error", ack.Data.Data)
+				}
+				return
+			}
+
+			if ack.Data.ID != "c1" {
+				t.Errorf("id = %q, want c1", ack.Data.ID)
+			}
+			for key, want := range tt.data {
+				if got := ack.Data.Data[key]; got != want {
+					t.Errorf("data[%s] = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCommandRemovedMember(t *testing.T) {
+	app, mock := newTestApp(t)
+
+	client := newTestClient(testProjectID, "t1", "Ada", 1)
+	app.hub.register(client)
+
+	// the subject was an editor when the websocket connected.
+	expectProject(mock, testProjectID, models.RoleNone)
+	mock.ExpectQuery(`SELECT role FROM project_members WHERE project_id = \? AND subject = \?`).
+		WithArgs(testProjectID, "ada-sub").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+
+	ctx := withSubject(httptest.NewRequest(http.MethodGet, "/api/v1/ws/"+testProjectID, nil), "ada-sub").Context()
+	message := `{"id":"c1","command":"DELETE_TASK","params":{"taskId":"` + testTaskID + `"}}`
+	app.handleCommand(ctx, client, []byte(message))
+
+	var ack struct {
+		Data wsAck `json:"data"`
+	}
+	err := json.Unmarshal((<-client.send).payload, &ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Data.Status != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", ack.Data.Status, http.StatusForbidden)
+	}
+}
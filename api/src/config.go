@@ -0,0 +1,459 @@
+package src
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dump.link/src/validation"
+)
+
+// config is the effective configuration of the server. Every setting has an
+// environment variable, which takes precedence over the same key in the
+// optional config file, which has the format of .env.dist. The flags
+// override both.
+type config struct {
+	Addr      string
+	Env       string
+	LogLevel  slog.Level
+	LogFormat string
+	AppURL    string
+
+	AllowedOrigins        []string
+	ContentSecurityPolicy string
+
+	DB          dbConfig
+	Server      serverConfig
+	OIDC        oidcConfig
+	RateLimit   rateLimitConfig
+	Broker      brokerConfig
+	Metrics     metricsConfig
+	Tracing     tracingConfig
+	ActivityTTL time.Duration
+
+	// AllowPrivateWebhooks lets notification targets point to loopback and
+	// private addresses, which are refused by default.
+	AllowPrivateWebhooks bool
+
+	Features featureConfig
+}
+
+type dbConfig struct {
+	User     string
+	Password string
+	Host     string
+	Name     string
+	TLS      string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	ConnectTimeout  time.Duration
+}
+
+// serverConfig holds the timeouts of the HTTP server. ShutdownTimeout bounds
+// how long in-flight requests, websockets and background tasks get to finish.
+type serverConfig struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+// metricsConfig exposes /metrics on a listener of its own, e.g. for the
+// scraper of the platform, or with a bearer token on the main one. Without
+// either, the metrics are not exposed.
+type metricsConfig struct {
+	Addr  string
+	Token string
+}
+
+// featureConfig turns optional features off, all of them are on by default.
+type featureConfig struct {
+	// Notifications posts changes to Slack and Mattermost webhooks.
+	Notifications bool
+	// Github closes tasks mentioned in merged PRs and commits.
+	Github bool
+	// SSE streams the project events without a websocket.
+	SSE bool
+}
+
+var defaultAllowedOrigins = []string{"http://localhost:1234", "http://localhost:8080", "https://kitchen.dump.link", "https://dump.link"}
+
+const defaultContentSecurityPolicy = "img-src 'self' https://lh3.googleusercontent.com;"
+
+// loadConfig reads the flags, the environment and the config file given with
+// -config or CONFIG_FILE.
+func loadConfig(args []string) (config, error) {
+	fs := flag.NewFlagSet("dump.link", flag.ContinueOnError)
+	addr := fs.String("addr", "", "HTTP network address, ADDR (default 0.0.0.0:8080)")
+	configFile := fs.String("config", "", "file with KEY=VALUE settings, CONFIG_FILE")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error, LOG_LEVEL")
+	logFormat := fs.String("log-format", "", "text or json, LOG_FORMAT")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return config{}, err
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	settings := map[string]string{}
+	if *configFile != "" {
+		settings, err = readConfigFile(*configFile)
+		if err != nil {
+			return config{}, err
+		}
+	}
+
+	getenv := func(name string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return settings[name]
+	}
+
+	cfg, err := configFromEnv(getenv)
+	if err != nil {
+		return cfg, err
+	}
+
+	if *addr != "" {
+		cfg.Addr = *addr
+	}
+	if *logLevel != "" {
+		err = cfg.LogLevel.UnmarshalText([]byte(*logLevel))
+		if err != nil {
+			return cfg, fmt.Errorf("-log-level: %w", err)
+		}
+	}
+	if *logFormat != "" {
+		if !validation.PermittedValue(*logFormat, "text", "json") {
+			return cfg, fmt.Errorf("-log-format: must be text or json, got %q", *logFormat)
+		}
+		cfg.LogFormat = *logFormat
+	}
+
+	return cfg, nil
+}
+
+// configFromEnv reads every setting with getenv, applies the defaults and
+// validates the result. It reports all invalid settings at once.
+func configFromEnv(getenv func(string) string) (config, error) {
+	r := &configReader{getenv: getenv, v: validation.New()}
+
+	cfg := config{
+		Addr:   r.string("ADDR", "0.0.0.0:8080"),
+		Env:    getenv("ENV"),
+		AppURL: strings.TrimRight(r.string("APP_URL", "https://dump.link"), "/"),
+
+		AllowedOrigins:        r.list("ALLOWED_ORIGINS", defaultAllowedOrigins),
+		ContentSecurityPolicy: r.string("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+
+		DB: dbConfig{
+			User:     getenv("DB_USER"),
+			Password: getenv("DB_PASS"),
+			Host:     getenv("DB_HOST"),
+			Name:     getenv("DB_NAME"),
+			TLS:      r.string("DB_TLS", "false"),
+
+			MaxOpenConns:    r.int("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    r.int("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxIdleTime: r.duration("DB_CONN_MAX_IDLE_TIME", 15*time.Minute),
+			ConnMaxLifetime: r.duration("DB_CONN_MAX_LIFETIME", time.Hour),
+			ConnectTimeout:  r.duration("DB_CONNECT_TIMEOUT", 10*time.Second),
+		},
+
+		Server: serverConfig{
+			ReadTimeout:     r.duration("HTTP_READ_TIMEOUT", 10*time.Second),
+			WriteTimeout:    r.duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:     r.duration("HTTP_IDLE_TIMEOUT", time.Minute),
+			ShutdownTimeout: r.duration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		},
+
+		OIDC:        oidcConfigFromEnv(getenv),
+		ActivityTTL: r.duration("ACTIVITY_TTL", defaultActivityTTL),
+
+		AllowPrivateWebhooks: r.bool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		Metrics: metricsConfig{
+			Addr:  getenv("METRICS_ADDR"),
+			Token: getenv("METRICS_TOKEN"),
+		},
+
+		Features: featureConfig{
+			Notifications: r.bool("FEATURE_NOTIFICATIONS", true),
+			Github:        r.bool("FEATURE_GITHUB", true),
+			SSE:           r.bool("FEATURE_SSE", true),
+		},
+	}
+
+	// the development setup logs everything.
+	defaultLogLevel := "info"
+	if cfg.Env == "" {
+		defaultLogLevel = "debug"
+	}
+	err := cfg.LogLevel.UnmarshalText([]byte(r.string("LOG_LEVEL", defaultLogLevel)))
+	r.v.Check(err == nil, "LOG_LEVEL", "must be debug, info, warn or error")
+	cfg.LogFormat = r.string("LOG_FORMAT", "text")
+	r.v.Check(validation.PermittedValue(cfg.LogFormat, "text", "json"), "LOG_FORMAT", "must be text or json")
+
+	cfg.RateLimit, err = rateLimitConfigFromEnv(getenv)
+	r.check(err)
+	cfg.Broker, err = brokerConfigFromEnv(getenv)
+	r.check(err)
+	cfg.Tracing, err = tracingConfigFromEnv(getenv)
+	r.check(err)
+
+	cfg.validate(r.v)
+
+	if !r.v.Valid() {
+		return cfg, validationError(r.v.Errors)
+	}
+	return cfg, nil
+}
+
+func (cfg *config) validate(v *validation.Validator) {
+	v.Check(validOrigin(cfg.AppURL), "APP_URL", "must be an http or https URL")
+	for _, origin := range cfg.AllowedOrigins {
+		v.Check(validOrigin(origin), "ALLOWED_ORIGINS", fmt.Sprintf("%q must be a scheme and host without a path", origin))
+	}
+
+	v.Check(cfg.Metrics.Addr != cfg.Addr, "METRICS_ADDR", "must differ from ADDR, use METRICS_TOKEN to serve the metrics there")
+
+	v.Check(validation.NotBlank(cfg.DB.Host), "DB_HOST", "must be provided")
+	v.Check(validation.NotBlank(cfg.DB.Name), "DB_NAME", "must be provided")
+	v.Check(cfg.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	v.Check(cfg.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	if cfg.DB.MaxOpenConns > 0 {
+		v.Check(cfg.DB.MaxIdleConns <= cfg.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS", "must not be more than DB_MAX_OPEN_CONNS")
+	}
+}
+
+// validOrigin reports whether the value is an http or https URL without a
+// path, e.g. https://dump.link.
+func validOrigin(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == ""
+}
+
+// validationError lists the invalid settings, sorted by name.
+func validationError(errs map[string]string) error {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("%s %s", name, errs[name]))
+	}
+	return fmt.Errorf("invalid config: %s", strings.Join(messages, "; "))
+}
+
+// configReader parses settings, collecting the errors instead of stopping at
+// the first one.
+type configReader struct {
+	getenv func(string) string
+	v      *validation.Validator
+}
+
+// check adds the error of a nested config reader, which starts with the name
+// of the setting.
+func (r *configReader) check(err error) {
+	if err == nil {
+		return
+	}
+	name, message, found := strings.Cut(err.Error(), ": ")
+	if !found {
+		name, message = "config", err.Error()
+	}
+	r.v.AddError(name, message)
+}
+
+func (r *configReader) string(name string, defaultValue string) string {
+	if value := r.getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (r *configReader) list(name string, defaultValue []string) []string {
+	if value := splitList(r.getenv(name)); len(value) > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func (r *configReader) int(name string, defaultValue int) int {
+	value := r.getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(value)
+	r.v.Check(err == nil, name, fmt.Sprintf("must be an integer, got %q", value))
+	return i
+}
+
+func (r *configReader) bool(name string, defaultValue bool) bool {
+	value := r.getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	r.v.Check(err == nil, name, fmt.Sprintf("must be true or false, got %q", value))
+	return b
+}
+
+// duration parses e.g. 30s or 15m, which must be positive.
+func (r *configReader) duration(name string, defaultValue time.Duration) time.Duration {
+	value := r.getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	r.v.Check(err == nil && d > 0, name, fmt.Sprintf("must be a positive duration like 30s, got %q", value))
+	return d
+}
+
+// readConfigFile reads KEY=VALUE lines like in .env.dist. Empty lines and
+// lines starting with # are skipped, values may be quoted.
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(text, "=")
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		if !found || key == "" {
+			return nil, fmt.Errorf("config file %s:%d: expected KEY=VALUE", path, line)
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+
+		settings[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	return settings, nil
+}
+
+// redacted hides secrets in the printed config.
+func redacted(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// logAttrs lists the effective settings for the startup log, with the
+// secrets redacted.
+func (cfg config) logAttrs() []any {
+	attrs := []any{
+		slog.String("addr", cfg.Addr),
+		slog.String("env", cfg.Env),
+		slog.String("logLevel", cfg.LogLevel.String()),
+		slog.String("logFormat", cfg.LogFormat),
+		slog.String("appURL", cfg.AppURL),
+		slog.String("allowedOrigins", strings.Join(cfg.AllowedOrigins, ",")),
+		slog.String("contentSecurityPolicy", cfg.ContentSecurityPolicy),
+		slog.Group("db",
+			slog.String("user", cfg.DB.User),
+			slog.String("password", redacted(cfg.DB.Password)),
+			slog.String("host", cfg.DB.Host),
+			slog.String("name", cfg.DB.Name),
+			slog.String("tls", cfg.DB.TLS),
+			slog.Int("maxOpenConns", cfg.DB.MaxOpenConns),
+			slog.Int("maxIdleConns", cfg.DB.MaxIdleConns),
+			slog.Duration("connMaxIdleTime", cfg.DB.ConnMaxIdleTime),
+			slog.Duration("connMaxLifetime", cfg.DB.ConnMaxLifetime),
+			slog.Duration("connectTimeout", cfg.DB.ConnectTimeout),
+		),
+		slog.Group("server",
+			slog.Duration("readTimeout", cfg.Server.ReadTimeout),
+			slog.Duration("writeTimeout", cfg.Server.WriteTimeout),
+			slog.Duration("idleTimeout", cfg.Server.IdleTimeout),
+			slog.Duration("shutdownTimeout", cfg.Server.ShutdownTimeout),
+		),
+		slog.Group("oidc",
+			slog.String("issuer", cfg.OIDC.Issuer),
+			slog.String("audience", strings.Join(cfg.OIDC.Audience, ",")),
+			slog.Any("algorithms", cfg.OIDC.Algorithms),
+			slog.String("jwksURL", cfg.OIDC.JWKSURL),
+			slog.String("jwksFile", cfg.OIDC.JWKSFile),
+			slog.String("usernameClaim", cfg.OIDC.UsernameClaim),
+			slog.String("scopeClaim", cfg.OIDC.ScopeClaim),
+			slog.String("emailClaim", cfg.OIDC.EmailClaim),
+		),
+		slog.Group("broker",
+			slog.String("kind", cfg.Broker.Kind),
+			slog.Duration("pollInterval", cfg.Broker.PollInterval),
+		),
+		slog.Group("metrics",
+			slog.String("addr", cfg.Metrics.Addr),
+			slog.String("token", redacted(cfg.Metrics.Token)),
+		),
+		slog.Group("tracing",
+			slog.String("exporter", cfg.Tracing.Exporter),
+			slog.String("endpoint", cfg.Tracing.Endpoint),
+			slog.Float64("sampleRatio", cfg.Tracing.SampleRatio),
+		),
+		slog.Duration("activityTTL", cfg.ActivityTTL),
+		slog.Bool("allowPrivateWebhooks", cfg.AllowPrivateWebhooks),
+		slog.Group("features",
+			slog.Bool("notifications", cfg.Features.Notifications),
+			slog.Bool("github", cfg.Features.Github),
+			slog.Bool("sse", cfg.Features.SSE),
+		),
+	}
+
+	limits := []any{
+		slog.Bool("enabled", cfg.RateLimit.Enabled),
+		slog.String("ipHeader", cfg.RateLimit.IPHeader),
+	}
+	for _, class := range routeClasses {
+		if limit, ok := cfg.RateLimit.PerIP[class]; ok {
+			limits = append(limits, slog.String("ip_"+string(class), limit.String()))
+		}
+		if limit, ok := cfg.RateLimit.PerProject[class]; ok {
+			limits = append(limits, slog.String("project_"+string(class), limit.String()))
+		}
+	}
+	attrs = append(attrs, slog.Group("rateLimit", limits...))
+
+	return attrs
+}
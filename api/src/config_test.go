@@ -0,0 +1,139 @@
+package src
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mapGetenv(settings map[string]string) func(string) string {
+	return func(name string) string {
+		return settings[name]
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	cfg, err := configFromEnv(mapGetenv(map[string]string{
+		"DB_HOST":            "localhost",
+		"DB_NAME":            "dumplink",
+		"HTTP_WRITE_TIMEOUT": "1m",
+		"ALLOWED_ORIGINS":    "https://dump.link, https://example.com",
+		"FEATURE_GITHUB":     "false",
+		"APP_URL":            "https://example.com/",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantServer := serverConfig{
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    time.Minute,
+		IdleTimeout:     time.Minute,
+		ShutdownTimeout: 20 * time.Second,
+	}
+	if cfg.Server != wantServer {
+		t.Errorf("Server = %+v, want %+v", cfg.Server, wantServer)
+	}
+	if want := []string{"https://dump.link", "https://example.com"}; !reflect.DeepEqual(cfg.AllowedOrigins, want) {
+		t.Errorf("AllowedOrigins = %v, want %v", cfg.AllowedOrigins, want)
+	}
+	if cfg.AppURL != "https://example.com" {
+		t.Errorf("AppURL = %q, want the trailing slash trimmed", cfg.AppURL)
+	}
+	if cfg.Features != (featureConfig{Notifications: true, Github: false, SSE: true}) {
+		t.Errorf("Features = %+v", cfg.Features)
+	}
+	if cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("LogLevel without ENV = %v, want debug", cfg.LogLevel)
+	}
+	if cfg.DB.MaxOpenConns != 25 || cfg.Addr != "0.0.0.0:8080" || cfg.Broker.Kind != "memory" {
+		t.Errorf("defaults were not applied: %+v", cfg)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	_, err := configFromEnv(mapGetenv(map[string]string{
+		"DB_HOST":            "localhost",
+		"ALLOWED_ORIGINS":    "https://dump.link/app",
+		"DB_MAX_OPEN_CONNS":  "10",
+		"DB_MAX_IDLE_CONNS":  "20",
+		"SHUTDOWN_TIMEOUT":   "-1s",
+		"FEATURE_SSE":        "maybe",
+		"LOG_LEVEL":          "loud",
+		"BROKER":             "carrier-pigeon",
+		"RATE_LIMIT_ENABLED": "sometimes",
+		"METRICS_ADDR":       "0.0.0.0:8080",
+	}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, name := range []string{"ALLOWED_ORIGINS", "DB_NAME", "DB_MAX_IDLE_CONNS", "SHUTDOWN_TIMEOUT", "FEATURE_SSE", "LOG_LEVEL", "BROKER", "RATE_LIMIT_ENABLED", "METRICS_ADDR"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(path, []byte(`
+# comment
+DB_HOST=db.internal
+DB_NAME="from file"
+DB_PASS='s3cret'
+ADDR=0.0.0.0:9000
+LOG_LEVEL=warn
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_NAME", "from env")
+	t.Setenv("ADDR", "0.0.0.0:9001")
+	for _, name := range []string{"DB_HOST", "DB_PASS", "LOG_LEVEL"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := loadConfig([]string{"-config", path, "-addr", "127.0.0.1:8081"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DB.Host != "db.internal" || cfg.DB.Password != "s3cret" {
+		t.Errorf("the file was not read: %+v", cfg.DB)
+	}
+	if cfg.DB.Name != "from env" {
+		t.Errorf("DB.Name = %q, want the environment to win over the file", cfg.DB.Name)
+	}
+	if cfg.Addr != "127.0.0.1:8081" {
+		t.Errorf("Addr = %q, want the flag to win", cfg.Addr)
+	}
+	if cfg.LogLevel != slog.LevelWarn {
+		t.Errorf("LogLevel = %v, want warn", cfg.LogLevel)
+	}
+
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).Info("effective config", cfg.logAttrs()...)
+	if strings.Contains(b.String(), "s3cret") {
+		t.Errorf("the printed config contains the password: %s", b.String())
+	}
+	if !strings.Contains(b.String(), "db.password=[redacted]") {
+		t.Errorf("the printed config does not show the password as redacted: %s", b.String())
+	}
+}
+
+func TestReadConfigFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("DB_HOST\n"), 0o600)
+
+	if _, err := readConfigFile(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("readConfigFile() error = %v, want the line number", err)
+	}
+}
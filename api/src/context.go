@@ -0,0 +1,54 @@
+package src
+
+import (
+	"context"
+	"net/http"
+
+	"dump.link/src/models"
+)
+
+type contextKey string
+
+const (
+	projectRoleContextKey  = contextKey("projectRole")
+	shareTokenIDContextKey = contextKey("shareTokenID")
+	requestIDContextKey    = contextKey("requestID")
+)
+
+func (app *application) contextSetProjectRole(r *http.Request, role string) *http.Request {
+	ctx := context.WithValue(r.Context(), projectRoleContextKey, role)
+	return r.WithContext(ctx)
+}
+
+// contextGetProjectRole returns the role requireProjectRole granted the request.
+func (app *application) contextGetProjectRole(r *http.Request) string {
+	role, ok := r.Context().Value(projectRoleContextKey).(string)
+	if !ok {
+		return models.RoleNone
+	}
+	return role
+}
+
+func (app *application) contextSetShareTokenID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), shareTokenIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// shareTokenIDFromContext returns the ID of the share token that let the
+// request in, if it was one.
+func shareTokenIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(shareTokenIDContextKey).(string)
+	return id
+}
+
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// requestIDFromContext returns the ID the requestID middleware gave the
+// request, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
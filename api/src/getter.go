@@ -1,17 +1,27 @@
 package src
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
+	"dump.link/src/validation"
 	"github.com/julienschmidt/httprouter"
 )
 
+// getAndValidateID returns the ID of the path parameter, responding with 404
+// if it does not exist. The role was granted for the project of the path, so
+// the IDs of buckets, tasks and the like have to belong to that project.
 func (app *application) getAndValidateID(w http.ResponseWriter, r *http.Request, idParamName string) (string, bool) {
 	params := httprouter.ParamsFromContext(r.Context())
 	id := params.ByName(idParamName)
 
-	if !app.idExists(idParamName, id) {
+	if idParamName != "projectId" && !validation.InProject(id, params.ByName("projectId")) {
+		app.notFoundResponse(w, r)
+		return "", false
+	}
+
+	if !app.idExists(r.Context(), idParamName, id) {
 		app.notFoundResponse(w, r)
 		return "", false
 	}
@@ -19,16 +29,22 @@ func (app *application) getAndValidateID(w http.ResponseWriter, r *http.Request,
 	return id, true
 }
 
-func (app *application) idExists(idType string, id string) bool {
+func (app *application) idExists(ctx context.Context, idType string, id string) bool {
 	switch idType {
 	case "projectId":
-		return app.projects.IDExists(id)
+		return app.projects.IDExists(ctx, id)
 	case "taskId":
-		return app.tasks.IDExists(id)
+		return app.tasks.IDExists(ctx, id)
 	case "dependencyId":
-		return app.buckets.IDExists(id)
+		return app.buckets.IDExists(ctx, id)
 	case "bucketId":
-		return app.buckets.IDExists(id)
+		return app.buckets.IDExists(ctx, id)
+	case "commentId":
+		return app.comments.IDExists(ctx, id)
+	case "shareId":
+		return app.shareTokens.IDExists(ctx, id)
+	case "targetId":
+		return app.notificationTargets.IDExists(ctx, id)
 	default:
 		return false
 	}
@@ -0,0 +1,82 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+)
+
+// otherProjectID is a project the caller of the tests has no role in.
+const otherProjectID = "Zz3De5Gh7Jk"
+
+func TestChildIDOfOtherProject(t *testing.T) {
+	otherTask := otherProjectID + "Ta5Sk7Mn9Pq"
+	otherBucket := otherProjectID + "Bc4Df6Hj8Km"
+	otherComment := otherProjectID + "Co5Mm7Nt9Qr"
+
+	tests := []struct {
+		name    string
+		method  string
+		route   string
+		path    string
+		handler func(*application, http.ResponseWriter, *http.Request)
+		// exists are the tables the handler checks IDs in before it gets
+		// to the one of the other project.
+		exists []string
+	}{
+		{"PatchTask", http.MethodPatch, "/api/v1/projects/:projectId/tasks/:taskId", "/tasks/" + otherTask, (*application).ApiPatchTask, nil},
+		{"DeleteTask", http.MethodDelete, "/api/v1/projects/:projectId/tasks/:taskId", "/tasks/" + otherTask, (*application).ApiDeleteTask, nil},
+		{"PatchBucket", http.MethodPatch, "/api/v1/projects/:projectId/buckets/:bucketId", "/buckets/" + otherBucket, (*application).ApiPatchBucket, nil},
+		{"ResetBucketLayers", http.MethodPost, "/api/v1/projects/:projectId/buckets/:bucketId/resetLayers", "/buckets/" + otherBucket + "/resetLayers", (*application).ApiResetBucketLayers, nil},
+		{"RemoveDependency", http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", "/dependencies/" + otherBucket + "/" + testBucketID, (*application).ApiRemoveDependency, []string{"projects"}},
+		{"RemoveOtherDependency", http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", "/dependencies/" + testBucketID + "/" + otherBucket, (*application).ApiRemoveDependency, []string{"projects", "buckets"}},
+		{"PatchComment", http.MethodPatch, "/api/v1/projects/:projectId/comments/:commentId", "/comments/" + otherComment, (*application).ApiPatchComment, []string{"projects"}},
+		{"DeleteComment", http.MethodDelete, "/api/v1/projects/:projectId/comments/:commentId", "/comments/" + otherComment, (*application).ApiDeleteComment, []string{"projects"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			for _, table := range tt.exists {
+				expectExists(mock, table, true)
+			}
+
+			r := httptest.NewRequest(tt.method, "/api/v1/projects/"+testProjectID+tt.path, strings.NewReader(`{"title":"hijacked"}`))
+			r.Header.Set("Username", "Mallory")
+			w := serveRoute(tt.route, func(w http.ResponseWriter, r *http.Request) { tt.handler(app, w, r) }, r)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCommandChildIDOfOtherProject(t *testing.T) {
+	app, mock := newTestApp(t)
+
+	client := newTestClient(testProjectID, "t1", "Mallory", 1)
+	app.hub.register(client)
+
+	expectProject(mock, testProjectID, models.RoleEditor)
+
+	message := `{"id":"c1","command":"DELETE_TASK","params":{"taskId":"` + otherProjectID + `Ta5Sk7Mn9Pq"}}`
+	app.handleCommand(context.Background(), client, []byte(message))
+
+	var ack struct {
+		Data wsAck `json:"data"`
+	}
+	err := json.Unmarshal((<-client.send).payload, &ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Data.Status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", ack.Data.Status, http.StatusNotFound)
+	}
+}
@@ -3,11 +3,11 @@ package src
 import (
 	"fmt"
 	"io/fs"
-	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
 
+	"dump.link/src/models"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -15,18 +15,6 @@ func (app *application) ProjectRoot(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-func (app *application) HealthGet(w http.ResponseWriter, r *http.Request) {
-	data := map[string]string{
-		"status": "available",
-	}
-
-	err := app.writeJSON(w, http.StatusOK, data, nil)
-	if err != nil {
-		app.logger.Error(err.Error())
-		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
-	}
-}
-
 func (app *application) PrivateGet(w http.ResponseWriter, r *http.Request) {
 	data := map[string]string{
 		"status": "private access",
@@ -44,7 +32,7 @@ func (app *application) RootGet(w http.ResponseWriter, r *http.Request) {
 
 	htmlContent, err := app.templatesFS.ReadFile(htmlTemplatePath)
 	if err != nil {
-		log.Printf("Error reading HTML template: %v", err)
+		app.logger.ErrorContext(r.Context(), "reading HTML template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -91,12 +79,28 @@ func (app *application) ProjectGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
+	// do not leak the name of projects the visitor may not see.
+	visible := project.AnonymousAccess != models.RoleNone
+	if token := shareTokenFromRequest(r); !visible && token != "" {
+		share, err := app.projectShareToken(r.Context(), projectId, token)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		visible = share != nil
+	}
+
+	if !visible {
+		app.genericPageResponse(w, r, "dump.link")
+		return
+	}
+
 	title := fmt.Sprintf("dump.link - %s", project.Name)
 	app.genericPageResponse(w, r, title)
 }
@@ -1,11 +1,10 @@
 package src
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
-	"dump.link/src/models"
+	"dump.link/src/validation"
 )
 
 func (app *application) ApiActivityPost(w http.ResponseWriter, r *http.Request) {
@@ -34,55 +33,51 @@ func (app *application) ApiActivityPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if input.BucketID != nil && input.TaskID != nil {
-		app.badRequestResponse(w, r, errors.New("too much fields"))
-		return
+	v := validation.New()
+	v.Check(input.BucketID == nil || input.TaskID == nil, "taskId", "must not be given together with bucketId")
+	if input.BucketID != nil {
+		v.Check(validation.InProject(*input.BucketID, projectId), "bucketId", "must be a bucket ID of this project")
+	}
+	if input.TaskID != nil {
+		v.Check(validation.InProject(*input.TaskID, projectId), "taskId", "must be a task ID of this project")
 	}
 
-	if input.TaskID != nil && !app.tasks.IDExists(*input.TaskID) {
-		app.notFoundResponse(w, r)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	if input.BucketID != nil && !app.buckets.IDExists(*input.BucketID) {
+	if input.TaskID != nil && !app.tasks.IDExists(r.Context(), *input.TaskID) {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	if input.TaskID != nil {
-		err = app.activities.ReplaceTaskId(projectId, *input.TaskID, username)
-	} else {
-		if input.BucketID != nil {
-			err = app.activities.ReplaceBucketId(projectId, *input.BucketID, username)
-		} else {
-			err = app.activities.Reset(projectId, username)
-		}
+	if input.BucketID != nil && !app.buckets.IDExists(r.Context(), *input.BucketID) {
+		app.notFoundResponse(w, r)
+		return
 	}
 
+	err = app.setActivity(r.Context(), projectId, username, input.BucketID, input.TaskID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	data, err := app.activities.GetForProjectId(projectId)
+	data, err := app.projectActivities(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	if data == nil {
-		data = []*models.Activity{}
-	}
-
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionUpdateActivities, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionUpdateActivities, data)
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.actions.Insert(projectId, nil, nil, startTime, string(ActionUpdateActivities), username)
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionUpdateActivities), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,134 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	maxAssignees          = 20
+	maxAssigneeNameLength = 255
+)
+
+type assignedTaskResponse struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Priority  int       `json:"priority"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type assignedBucketResponse struct {
+	ID    string                 `json:"id"`
+	Name  string                 `json:"name"`
+	Tasks []assignedTaskResponse `json:"tasks"`
+}
+
+type assignedProjectResponse struct {
+	ID      string                    `json:"id"`
+	Name    string                    `json:"name"`
+	Buckets []*assignedBucketResponse `json:"buckets"`
+}
+
+// ApiGetAssignedTasks returns the open tasks of a user, grouped by project and
+// bucket. The project ID is all it takes to open a project, so only projects
+// the caller is a member of are listed, never the ones merely open to
+// everybody with the link.
+func (app *application) ApiGetAssignedTasks(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	username := params.ByName("username")
+
+	if username == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	memberOf, err := app.members.GetProjectIdsForSubject(r.Context(), subjectFromRequest(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	visible := make(map[string]bool)
+	for _, id := range memberOf {
+		visible[id] = true
+	}
+
+	assigned, err := app.taskAssignees.GetOpenForUsername(r.Context(), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the rows are ordered by project and bucket, so grouping only has to
+	// look at the last entry.
+	projects := []*assignedProjectResponse{}
+	for _, a := range assigned {
+		if !visible[a.ProjectID] {
+			continue
+		}
+
+		if len(projects) == 0 || projects[len(projects)-1].ID != a.ProjectID {
+			projects = append(projects, &assignedProjectResponse{ID: a.ProjectID, Name: a.ProjectName})
+		}
+		project := projects[len(projects)-1]
+
+		if len(project.Buckets) == 0 || project.Buckets[len(project.Buckets)-1].ID != a.BucketID {
+			name := a.BucketName
+			if a.BucketDump {
+				name = "Dump"
+			}
+			project.Buckets = append(project.Buckets, &assignedBucketResponse{ID: a.BucketID, Name: name})
+		}
+		bucket := project.Buckets[len(project.Buckets)-1]
+
+		bucket.Tasks = append(bucket.Tasks, assignedTaskResponse{
+			ID:        a.Task.ID,
+			Title:     a.Task.Title,
+			Priority:  a.Task.Priority,
+			UpdatedAt: a.Task.UpdatedAt,
+		})
+	}
+
+	data := envelope{
+		"username": username,
+		"projects": projects,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// normalizeAssignees trims and deduplicates the usernames, keeping their order.
+func normalizeAssignees(usernames []string) ([]string, error) {
+	seen := make(map[string]bool)
+	assignees := []string{}
+
+	for _, username := range usernames {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			return nil, errors.New("assignees must not be empty")
+		}
+		if utf8.RuneCountInString(username) > maxAssigneeNameLength {
+			return nil, fmt.Errorf("assignees must not be longer than %d characters", maxAssigneeNameLength)
+		}
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		assignees = append(assignees, username)
+	}
+
+	if len(assignees) > maxAssignees {
+		return nil, fmt.Errorf("a task can not have more than %d assignees", maxAssignees)
+	}
+
+	return assignees, nil
+}
@@ -0,0 +1,45 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApiGetAssignedTasks(t *testing.T) {
+	app, mock := newTestApp(t)
+
+	const otherProjectID = "Zz3De5Gh7Jk"
+
+	mock.ExpectQuery(`SELECT project_id FROM project_members WHERE subject = \?`).
+		WithArgs("auth0|ada").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow(testProjectID))
+	// Grace is assigned in a project Ada is no member of, which is open to
+	// everybody with the link.
+	mock.ExpectQuery(`FROM task_assignees`).
+		WithArgs("Grace").
+		WillReturnRows(sqlmock.NewRows([]string{"p.id", "p.name", "b.id", "b.name", "b.dump", "t.id", "t.title", "t.notes", "t.closed", "t.bucket_id", "t.priority", "t.created_at", "t.updated_at", "t.updated_by"}).
+			AddRow(otherProjectID, "Secret", otherProjectID+"Bc4Df6Hj8Km", "Launch", false, otherProjectID+"Bc4Df6Hj8KmTa5Sk7Mn9Pq", "Pitch", "", false, otherProjectID+"Bc4Df6Hj8Km", 0, testCreatedAt, testCreatedAt, "Grace").
+			AddRow(testProjectID, "Checkout", testBucketID, "Payment", false, testTaskID, "Add PayPal", "", false, testBucketID, 0, testCreatedAt, testCreatedAt, "Ada"))
+
+	r := withSubject(httptest.NewRequest(http.MethodGet, "/api/v1/users/Grace/tasks", nil), "auth0|ada")
+	w := serveRoute("/api/v1/users/:username/tasks", app.ApiGetAssignedTasks, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var body struct {
+		Projects []assignedProjectResponse `json:"projects"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Projects) != 1 || body.Projects[0].ID != testProjectID {
+		t.Errorf("projects = %+v, want only %s", body.Projects, testProjectID)
+	}
+}
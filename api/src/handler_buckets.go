@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"dump.link/src/validation"
 )
 
 func (app *application) ApiPatchBucket(w http.ResponseWriter, r *http.Request) {
@@ -25,7 +27,7 @@ func (app *application) ApiPatchBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -37,10 +39,12 @@ func (app *application) ApiPatchBucket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		Name    *string `json:"name,omitempty"`
-		Done    *bool   `json:"done,omitempty"`
-		Layer   *int    `json:"layer,omitempty"`
-		Flagged *bool   `json:"flagged,omitempty"`
+		Name             *string `json:"name,omitempty"`
+		DefinitionOfDone *string `json:"definitionOfDone,omitempty"`
+		Done             *bool   `json:"done,omitempty"`
+		Layer            *int    `json:"layer,omitempty"`
+		Flagged          *bool   `json:"flagged,omitempty"`
+		appFields
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -49,10 +53,25 @@ func (app *application) ApiPatchBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validation.New()
+	if input.Name != nil {
+		v.Check(validation.MaxChars(*input.Name, validation.MaxNameLength), "name", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	}
+	if input.DefinitionOfDone != nil {
+		v.Check(validation.MaxChars(*input.DefinitionOfDone, maxDefinitionOfDoneLength), "definitionOfDone", fmt.Sprintf("must not be more than %d characters long", maxDefinitionOfDoneLength))
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	data := make(envelope)
 	if input.Name != nil {
 		data["name"] = *input.Name
 	}
+	if input.DefinitionOfDone != nil {
+		data["definition_of_done"] = *input.DefinitionOfDone
+	}
 	if input.Done != nil {
 		data["done"] = *input.Done
 	}
@@ -70,20 +89,27 @@ func (app *application) ApiPatchBucket(w http.ResponseWriter, r *http.Request) {
 
 	data["updated_by"] = username
 
-	err = app.buckets.Update(bucketId, data)
+	err = app.buckets.Update(r.Context(), bucketId, data)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	// fix missmatch between database column and frontend fields.
+	if data["definition_of_done"] != nil {
+		data["definitionOfDone"] = data["definition_of_done"]
+		delete(data, "definition_of_done")
+	}
+
 	//always send the id, ws needs it.
 	data["id"] = bucketId
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionUpdateBucket, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionUpdateBucket, data)
+	app.notify(r.Context(), projectId, ActionUpdateBucket, data, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	err = app.actions.Insert(projectId, &bucketId, nil, startTime, string(ActionUpdateBucket), username)
+	err = app.actions.Insert(r.Context(), projectId, &bucketId, nil, startTime, string(ActionUpdateBucket), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -109,7 +135,7 @@ func (app *application) ApiResetBucketLayers(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -120,7 +146,7 @@ func (app *application) ApiResetBucketLayers(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.buckets.ResetLayer(bucketId)
+	err = app.buckets.ResetLayer(r.Context(), bucketId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -131,10 +157,11 @@ func (app *application) ApiResetBucketLayers(w http.ResponseWriter, r *http.Requ
 	data["layer"] = nil
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionResetBucketLayers, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionResetBucketLayers, data)
+	app.notify(r.Context(), projectId, ActionResetBucketLayers, data, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	err = app.actions.Insert(projectId, &bucketId, nil, startTime, string(ActionResetBucketLayers), username)
+	err = app.actions.Insert(r.Context(), projectId, &bucketId, nil, startTime, string(ActionResetBucketLayers), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
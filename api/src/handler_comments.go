@@ -0,0 +1,302 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+)
+
+const maxCommentLength = 10000
+
+func (app *application) ApiGetComments(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	var bucketId, taskId *string
+	if id := r.URL.Query().Get("bucketId"); id != "" {
+		bucketId = &id
+	}
+	if id := r.URL.Query().Get("taskId"); id != "" {
+		taskId = &id
+	}
+
+	if (bucketId == nil) == (taskId == nil) {
+		app.badRequestResponse(w, r, errors.New("exactly one of bucketId and taskId is required"))
+		return
+	}
+
+	comments, err := app.comments.GetForSubject(r.Context(), projectId, bucketId, taskId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, comments, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) ApiPostComment(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if project.Archived {
+		app.goneResponse(w, r)
+		return
+	}
+
+	var input struct {
+		BucketID *string `json:"bucketId"`
+		TaskID   *string `json:"taskId"`
+		Body     string  `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	v.Check((input.BucketID == nil) != (input.TaskID == nil), "taskId", "exactly one of bucketId and taskId is required")
+	validateCommentBody(v, input.Body)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.commentSubjectInProject(r.Context(), projectId, input.BucketID, input.TaskID) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	commentId, err := app.comments.Insert(r.Context(), projectId, input.BucketID, input.TaskID, input.Body, username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	comment, err := app.comments.Get(r.Context(), commentId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	senderToken := app.getTokenFromRequest(r)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionAddComment, comment)
+	app.writeJSON(w, http.StatusCreated, comment, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, input.BucketID, input.TaskID, startTime, string(ActionAddComment), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) ApiPatchComment(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	commentId, valid := app.getAndValidateID(w, r, "commentId")
+	if !valid {
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if project.Archived {
+		app.goneResponse(w, r)
+		return
+	}
+
+	comment, err := app.comments.Get(r.Context(), commentId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if comment.ProjectID != projectId {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if comment.CreatedBy != username {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	if validateCommentBody(v, input.Body); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.comments.UpdateBody(r.Context(), commentId, input.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"id":   commentId,
+		"body": input.Body,
+	}
+
+	senderToken := app.getTokenFromRequest(r)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionUpdateComment, data)
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, comment.BucketID, comment.TaskID, startTime, string(ActionUpdateComment), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) ApiDeleteComment(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	commentId, valid := app.getAndValidateID(w, r, "commentId")
+	if !valid {
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if project.Archived {
+		app.goneResponse(w, r)
+		return
+	}
+
+	comment, err := app.comments.Get(r.Context(), commentId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if comment.ProjectID != projectId {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if comment.CreatedBy != username {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	err = app.comments.Delete(r.Context(), commentId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the clients need the subject to keep the counts right.
+	data := envelope{
+		"id":       commentId,
+		"bucketId": comment.BucketID,
+		"taskId":   comment.TaskID,
+	}
+
+	senderToken := app.getTokenFromRequest(r)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionDeleteComment, data)
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, comment.BucketID, comment.TaskID, startTime, string(ActionDeleteComment), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// commentSubjectInProject checks that the bucket or task exists and belongs to the project.
+func (app *application) commentSubjectInProject(ctx context.Context, projectId string, bucketId, taskId *string) bool {
+	id := ""
+	if bucketId != nil {
+		id = *bucketId
+	} else {
+		task, err := app.tasks.Get(ctx, *taskId)
+		if err != nil {
+			return false
+		}
+		id = task.BucketID
+	}
+
+	bucket, err := app.buckets.Get(ctx, id)
+	if err != nil {
+		return false
+	}
+
+	return bucket.ProjectID == projectId
+}
+
+func validateCommentBody(v *validation.Validator, body string) {
+	v.Check(validation.NotBlank(body), "body", "must be provided")
+	v.Check(validation.MaxChars(body, maxCommentLength), "body", fmt.Sprintf("must not be more than %d characters long", maxCommentLength))
+}
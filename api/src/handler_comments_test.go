@@ -0,0 +1,166 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const testCommentID = testProjectID + "Co5Mm7Nt9Qr"
+
+func testComment(createdBy string) *models.Comment {
+	taskId := testTaskID
+	return &models.Comment{
+		ID:        testCommentID,
+		ProjectID: testProjectID,
+		TaskID:    &taskId,
+		Body:      "Looks good",
+		CreatedBy: createdBy,
+	}
+}
+
+func TestApiPostComment(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// subject is the project of the commented bucket, if it is looked up.
+		subject string
+		want    int
+	}{
+		{"Task", `{"taskId":"` + testTaskID + `","body":"Looks good"}`, testProjectID, http.StatusCreated},
+		{"Blank", `{"taskId":"` + testTaskID + `","body":"  "}`, "", http.StatusUnprocessableEntity},
+		{"NoSubject", `{"body":"Looks good"}`, "", http.StatusUnprocessableEntity},
+		{"OtherProject", `{"taskId":"` + testTaskID + `","body":"Looks good"}`, "Zz3De5Gh7Jk", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			expectExists(mock, "projects", true)
+			expectProject(mock, testProjectID, models.RoleEditor)
+			if tt.subject != "" {
+				expectTask(mock, testTaskID, testBucketID, "Add PayPal")
+				expectBucket(mock, testBucketID, tt.subject)
+			}
+			if tt.want == http.StatusCreated {
+				expectExists(mock, "comments", false)
+				mock.ExpectExec(`INSERT INTO comments`).
+					WithArgs(sqlmock.AnyArg(), testProjectID, nil, testTaskID, "Looks good", "Ada").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectComment(mock, testComment("Ada"))
+				expectAction(mock, ActionAddComment)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/projects/"+testProjectID+"/comments", strings.NewReader(tt.body))
+			r.Header.Set("Username", "Ada")
+			w := serveRoute("/api/v1/projects/:projectId/comments", app.ApiPostComment, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+
+			var comment models.Comment
+			err := json.NewDecoder(w.Body).Decode(&comment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if comment.ID != testCommentID || comment.CreatedBy != "Ada" || comment.TaskID == nil || *comment.TaskID != testTaskID {
+				t.Errorf("comment = %+v", comment)
+			}
+		})
+	}
+}
+
+func TestApiPatchComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		comment  *models.Comment
+		body     string
+		want     int
+	}{
+		{"Author", "Ada", testComment("Ada"), `{"body":"Edited"}`, http.StatusOK},
+		{"SomebodyElse", "Grace", testComment("Ada"), `{"body":"Edited"}`, http.StatusForbidden},
+		{"Blank", "Ada", testComment("Ada"), `{"body":""}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			expectExists(mock, "projects", true)
+			expectExists(mock, "comments", true)
+			expectProject(mock, testProjectID, models.RoleEditor)
+			expectComment(mock, tt.comment)
+			if tt.want == http.StatusOK {
+				mock.ExpectExec(`UPDATE comments SET body = \? WHERE id = \?`).
+					WithArgs("Edited", testCommentID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAction(mock, ActionUpdateComment)
+			}
+
+			r := httptest.NewRequest(http.MethodPatch, "/api/v1/projects/"+testProjectID+"/comments/"+testCommentID, strings.NewReader(tt.body))
+			r.Header.Set("Username", tt.username)
+			w := serveRoute("/api/v1/projects/:projectId/comments/:commentId", app.ApiPatchComment, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestApiDeleteComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		comment  *models.Comment
+		want     int
+	}{
+		{"Author", "Ada", testComment("Ada"), http.StatusOK},
+		{"SomebodyElse", "Grace", testComment("Ada"), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			// the other clients of the project learn about the deletion.
+			other := newTestClient(testProjectID, "t2", "Grace", 1)
+			app.hub.register(other)
+
+			expectExists(mock, "projects", true)
+			expectExists(mock, "comments", true)
+			expectProject(mock, testProjectID, models.RoleEditor)
+			expectComment(mock, tt.comment)
+			if tt.want == http.StatusOK {
+				mock.ExpectExec(`DELETE FROM comments WHERE id = \?`).
+					WithArgs(testCommentID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAction(mock, ActionDeleteComment)
+			}
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/projects/"+testProjectID+"/comments/"+testCommentID+"?token=t1", nil)
+			r.Header.Set("Username", tt.username)
+			w := serveRoute("/api/v1/projects/:projectId/comments/:commentId", app.ApiDeleteComment, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			broadcast := len(other.send) > 0
+			if broadcast != (tt.want == http.StatusOK) {
+				t.Errorf("broadcast = %v, want %v", broadcast, tt.want == http.StatusOK)
+			}
+		})
+	}
+}
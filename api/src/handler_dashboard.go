@@ -0,0 +1,119 @@
+package src
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+)
+
+// recentlyVisitedDays is how long a project stays on the dashboard of somebody
+// who opened it without being a member.
+const recentlyVisitedDays = 30
+
+type dashboardProject struct {
+	*models.DashboardProject
+	// Relation is owner, editor or viewer for members and visited otherwise.
+	Relation string `json:"relation"`
+	// AppetiteUsed is the share of the time box that passed, 0 without one.
+	AppetiteUsed float64 `json:"appetiteUsed"`
+	// DaysRemaining is nil for projects without a time box.
+	DaysRemaining *int `json:"daysRemaining"`
+}
+
+// ApiGetDashboard lists the projects of the user: the ones they own or are a
+// member of, and the ones they recently opened. Both are looked up by the
+// subject of the token, never by the Username header anybody can set.
+func (app *application) ApiGetDashboard(w http.ResponseWriter, r *http.Request) {
+	subject := subjectFromRequest(r)
+
+	query := r.URL.Query()
+	v := validation.New()
+
+	filters := models.Filters{
+		Page:         readInt(query.Get("page"), 1, "page", v),
+		PageSize:     readInt(query.Get("pageSize"), 20, "pageSize", v),
+		Sort:         query.Get("sort"),
+		SortSafelist: models.DashboardSortSafelist,
+	}
+	if filters.Sort == "" {
+		filters.Sort = "-lastActivity"
+	}
+
+	v.Check(validation.Between(filters.Page, 1, 10_000), "page", "must be between 1 and 10000")
+	v.Check(validation.Between(filters.PageSize, 1, 100), "pageSize", "must be between 1 and 100")
+	_, safe := models.DashboardSortSafelist[strings.TrimPrefix(filters.Sort, "-")]
+	v.Check(safe, "sort", "must be name, createdAt, lastActivity or lastVisited, optionally prefixed with -")
+
+	var archived *bool
+	if value := query.Get("archived"); value != "" {
+		b, err := strconv.ParseBool(value)
+		v.Check(err == nil, "archived", "must be true or false")
+		archived = &b
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now()
+	visitedSince := now.AddDate(0, 0, -recentlyVisitedDays)
+
+	projects, metadata, err := app.dashboard.GetForUser(r.Context(), subject, visitedSince, archived, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	list := make([]dashboardProject, 0, len(projects))
+	for _, project := range projects {
+		entry := dashboardProject{
+			DashboardProject: project,
+			Relation:         project.Role,
+		}
+		if entry.Relation == "" {
+			entry.Relation = "visited"
+		}
+
+		if end, ok := appetiteEnd(project.Project); ok {
+			entry.AppetiteUsed = appetiteProgress(project.Project, now)
+			days := int(math.Ceil(end.Sub(now).Hours() / 24))
+			if days < 0 {
+				days = 0
+			}
+			entry.DaysRemaining = &days
+		}
+
+		list = append(list, entry)
+	}
+
+	data := envelope{
+		"projects": list,
+		"metadata": metadata,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readInt parses an optional integer query parameter.
+func readInt(value string, defaultValue int, key string, v *validation.Validator) int {
+	if value == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
@@ -0,0 +1,44 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApiGetDashboard(t *testing.T) {
+	app, mock := newTestApp(t)
+
+	// members and visits are both looked up by the subject, whatever the
+	// Username header claims.
+	mock.ExpectQuery(`FROM project_members WHERE subject = \?.*FROM project_visits\s+WHERE subject = \?`).
+		WithArgs("ada-sub", "ada-sub", sqlmock.AnyArg(), models.RoleNone, nil, nil, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "created_at", "ending_at", "updated_at", "appetite", "archived", "updated_by", "anonymous_access",
+			"role", "last_visited_at", "last_activity_at", "buckets", "done_buckets", "flagged_buckets", "total"}).
+			AddRow(testProjectID, "Checkout", "2024-03-01", testCreatedAt, nil, testCreatedAt, 0, false, "Ada", models.RoleEditor,
+				nil, testCreatedAt, testCreatedAt, 2, 1, 0, 1))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil)
+	r.Header.Set("Username", "Grace")
+	r = withSubject(r, "ada-sub")
+	w := serveRoute("/api/v1/dashboard", app.ApiGetDashboard, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var data struct {
+		Projects []dashboardProject `json:"projects"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Projects) != 1 || data.Projects[0].Relation != "visited" {
+		t.Errorf("projects = %+v, want the visited project", data.Projects)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"dump.link/src/validation"
 )
 
 func (app *application) ApiAddDependency(w http.ResponseWriter, r *http.Request) {
@@ -20,7 +22,7 @@ func (app *application) ApiAddDependency(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -31,8 +33,8 @@ func (app *application) ApiAddDependency(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	var input struct {
-		BucketID     *string `json:"bucketId"`
-		DependencyId *string `json:"dependencyId"`
+		BucketID     string `json:"bucketId"`
+		DependencyId string `json:"dependencyId"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -41,17 +43,22 @@ func (app *application) ApiAddDependency(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !app.buckets.IDExists(*input.BucketID) || !app.buckets.IDExists(*input.DependencyId) {
-		app.notFoundResponse(w, r)
+	v := validation.New()
+	v.Check(validation.InProject(input.BucketID, projectId), "bucketId", "must be a bucket ID of this project")
+	v.Check(validation.InProject(input.DependencyId, projectId), "dependencyId", "must be a bucket ID of this project")
+	v.Check(input.BucketID != input.DependencyId, "dependencyId", "must not be the bucket itself")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	if len(*input.BucketID) != 22 || len(*input.DependencyId) != 22 {
-		app.serverErrorResponse(w, r, fmt.Errorf("BucketID and DependsOnBucketID must be exactly 22 characters long"))
+	if !app.buckets.IDExists(r.Context(), input.BucketID) || !app.buckets.IDExists(r.Context(), input.DependencyId) {
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	exists, err := app.dependencies.Exists(*input.BucketID, *input.DependencyId)
+	exists, err := app.dependencies.Exists(r.Context(), input.BucketID, input.DependencyId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -61,23 +68,24 @@ func (app *application) ApiAddDependency(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.dependencies.Insert(*input.BucketID, *input.DependencyId, username)
+	err = app.dependencies.Insert(r.Context(), input.BucketID, input.DependencyId, username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	data := envelope{
-		"bucketId":     *input.BucketID,
-		"dependencyId": *input.DependencyId,
+		"bucketId":     input.BucketID,
+		"dependencyId": input.DependencyId,
 		"createdBy":    username,
 	}
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionAddBucketDependency, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionAddBucketDependency, data)
+	app.notify(r.Context(), projectId, ActionAddBucketDependency, data, username)
 	app.writeJSON(w, http.StatusCreated, data, nil)
 
-	err = app.actions.Insert(projectId, input.BucketID, nil, startTime, string(ActionAddBucketDependency), username)
+	err = app.actions.Insert(r.Context(), projectId, &input.BucketID, nil, startTime, string(ActionAddBucketDependency), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -108,7 +116,7 @@ func (app *application) ApiRemoveDependency(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -119,7 +127,7 @@ func (app *application) ApiRemoveDependency(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	rowsAffected, err := app.dependencies.Delete(bucketId, dependencyId)
+	rowsAffected, err := app.dependencies.Delete(r.Context(), bucketId, dependencyId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -136,10 +144,11 @@ func (app *application) ApiRemoveDependency(w http.ResponseWriter, r *http.Reque
 	}
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionRemoveBucketDependency, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionRemoveBucketDependency, data)
+	app.notify(r.Context(), projectId, ActionRemoveBucketDependency, data, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	err = app.actions.Insert(projectId, &bucketId, nil, startTime, string(ActionRemoveBucketDependency), username)
+	err = app.actions.Insert(r.Context(), projectId, &bucketId, nil, startTime, string(ActionRemoveBucketDependency), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
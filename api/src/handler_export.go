@@ -0,0 +1,114 @@
+package src
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"sort"
+
+	"dump.link/src/models"
+)
+
+type exportTask struct {
+	Title  string
+	Closed bool
+	Notes  template.HTML
+}
+
+type exportBucket struct {
+	Name             string
+	Done             bool
+	Flagged          bool
+	DefinitionOfDone template.HTML
+	Tasks            []exportTask
+}
+
+// ApiProjectExport renders the project as a standalone HTML page, with notes
+// and definitions of done converted from Markdown.
+func (app *application) ApiProjectExport(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	buckets, err := app.buckets.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	tasks, err := app.tasks.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Priority < tasks[j].Priority
+	})
+
+	tasksByBucket := make(map[string][]*models.Task)
+	for _, task := range tasks {
+		tasksByBucket[task.BucketID] = append(tasksByBucket[task.BucketID], task)
+	}
+
+	var exportBuckets []exportBucket
+	for _, bucket := range buckets {
+		if len(tasksByBucket[bucket.ID]) == 0 && bucket.DefinitionOfDone == "" {
+			continue
+		}
+
+		b := exportBucket{
+			Name:    bucketName(bucket),
+			Done:    bucket.Done,
+			Flagged: bucket.Flagged,
+		}
+
+		b.DefinitionOfDone, err = renderMarkdown(bucket.DefinitionOfDone)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, task := range tasksByBucket[bucket.ID] {
+			notes, err := renderMarkdown(task.Notes)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			b.Tasks = append(b.Tasks, exportTask{Title: task.Title, Closed: task.Closed, Notes: notes})
+		}
+
+		exportBuckets = append(exportBuckets, b)
+	}
+
+	tmpl, err := template.ParseFS(app.templatesFS, "templates/export.html")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := struct {
+		Project *models.Project
+		Buckets []exportBucket
+	}{
+		Project: project,
+		Buckets: exportBuckets,
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,304 @@
+package src
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"dump.link/src/models"
+)
+
+// maxGithubPayloadSize caps the webhook body. GitHub caps payloads at 25 MB,
+// but pull_request and push events are far smaller.
+const maxGithubPayloadSize = 5 << 20
+
+// maxTaskLinkTitleLength is the size of task_links.title. PR titles and
+// commit subjects have no limit.
+const maxTaskLinkTitleLength = 255
+
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		HTMLURL string `json:"html_url"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		Merged  bool   `json:"merged"`
+	} `json:"pull_request"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+type githubPushEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	} `json:"commits"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// githubReference is a merged PR or commit that mentions tasks of the project.
+type githubReference struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// ApiGithubSecretPost creates a new webhook secret for the project, replacing
+// the previous one. The secret is only shown once, but it is stored in plain
+// text, verifying the signatures needs it.
+func (app *application) ApiGithubSecretPost(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	secret, err := models.NewSecret(32)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.projects.Update(r.Context(), projectId, map[string]interface{}{"github_secret": secret})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"url":    fmt.Sprintf("%s/api/v1/projects/%s/github", app.config.AppURL, projectId),
+		"secret": secret,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionCreateGithubSecret), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// ApiGithubWebhook receives pull_request and push events. Every task of the
+// project mentioned in a merged PR or a commit to the default branch gets a
+// link to it and is closed.
+func (app *application) ApiGithubWebhook(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	secret, err := app.projects.GetGithubSecret(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGithubPayloadSize))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if secret == "" || !validGithubSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		app.errorResponse(w, r, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if project.Archived {
+		app.goneResponse(w, r)
+		return
+	}
+
+	var (
+		references []githubReference
+		username   string
+	)
+
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		app.writeJSON(w, http.StatusOK, envelope{"message": "pong"}, nil)
+		return
+	case "pull_request":
+		var input githubPullRequestEvent
+		err = json.Unmarshal(body, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		username = input.Sender.Login
+		if input.Action == "closed" && input.PullRequest.Merged {
+			references = append(references, githubReference{
+				URL:   input.PullRequest.HTMLURL,
+				Title: linkTitle(input.PullRequest.Title),
+				Text:  input.PullRequest.Title + "\n" + input.PullRequest.Body,
+			})
+		}
+	case "push":
+		var input githubPushEvent
+		err = json.Unmarshal(body, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		username = input.Sender.Login
+		if input.Ref == "refs/heads/"+input.Repository.DefaultBranch {
+			for _, commit := range input.Commits {
+				title, _, _ := strings.Cut(commit.Message, "\n")
+				references = append(references, githubReference{
+					URL:   commit.URL,
+					Title: linkTitle(title),
+					Text:  commit.Message,
+				})
+			}
+		}
+	default:
+		app.badRequestResponse(w, r, fmt.Errorf("unsupported event %q", event))
+		return
+	}
+
+	closed := []string{}
+	for _, ref := range references {
+		for _, taskId := range mentionedTaskIDs(projectId, ref.Text) {
+			if !app.tasks.IDExists(r.Context(), taskId) {
+				continue
+			}
+
+			err = app.linkAndCloseTask(r.Context(), projectId, taskId, ref, username)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			closed = append(closed, taskId)
+		}
+	}
+
+	data := envelope{
+		"closed": closed,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, taskId := range closed {
+		err = app.actions.Insert(r.Context(), projectId, nil, &taskId, startTime, string(ActionUpdateTask), username)
+		if err != nil {
+			app.logger.Info(fmt.Sprintf("Error logging GitHub action: %v", err))
+		}
+	}
+}
+
+func (app *application) linkAndCloseTask(ctx context.Context, projectId string, taskId string, ref githubReference, username string) error {
+	exists, err := app.taskLinks.Exists(ctx, taskId, ref.URL)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		err = app.taskLinks.Insert(ctx, taskId, ref.URL, ref.Title, username)
+		if err != nil {
+			return err
+		}
+
+		link := envelope{
+			"taskId":    taskId,
+			"url":       ref.URL,
+			"title":     ref.Title,
+			"createdBy": username,
+		}
+		app.sendActionDataToProjectClients(ctx, projectId, "", ActionAddTaskLink, link)
+	}
+
+	closed := true
+	_, err = app.updateTask(ctx, projectId, taskId, taskUpdate{Closed: &closed}, username, "")
+	if err != nil && !errors.Is(err, errNoUpdates) {
+		return err
+	}
+
+	return nil
+}
+
+// linkTitle shortens the title of a PR or commit to fit into a task link.
+func linkTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) <= maxTaskLinkTitleLength {
+		return title
+	}
+	return string([]rune(title)[:maxTaskLinkTitleLength-1]) + "…"
+}
+
+// validGithubSignature checks the X-Hub-Signature-256 header, which is the
+// hex encoded HMAC-SHA256 of the body prefixed with "sha256=".
+func validGithubSignature(secret string, body []byte, header string) bool {
+	signature, found := strings.CutPrefix(header, "sha256=")
+	if !found {
+		return false
+	}
+
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// mentionedTaskIDs finds the IDs of the project's tasks in the text. Task IDs
+// are the project ID followed by 11 base58 characters.
+func mentionedTaskIDs(projectId string, text string) []string {
+	pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(projectId) + `[1-9A-HJ-NP-Za-km-z]{11}\b`)
+
+	seen := make(map[string]bool)
+	ids := []string{}
+	for _, id := range pattern.FindAllString(text, -1) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,93 @@
+package src
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidGithubSignature(t *testing.T) {
+	secret := "It's a Secret to Everybody"
+	body := []byte("Hello, World!")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"Valid", secret, signature, true},
+		{"WrongSecret", "another secret", signature, false},
+		{"MissingPrefix", secret, signature[len("sha256="):], false},
+		{"NotHex", secret, "sha256=zz", false},
+		{"Empty", secret, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validGithubSignature(tt.secret, body, tt.header); got != tt.want {
+				t.Errorf("validGithubSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionedTaskIDs(t *testing.T) {
+	projectId := "Ab3De5Gh7Jk"
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"None", "Fix typo", []string{}},
+		{"Single", "Closes Ab3De5Gh7JkMn9Pq2Rs4Tu", []string{"Ab3De5Gh7JkMn9Pq2Rs4Tu"}},
+		{"Url", "https://dump.link/a/Ab3De5Gh7Jk?task=Ab3De5Gh7JkMn9Pq2Rs4Tu", []string{"Ab3De5Gh7JkMn9Pq2Rs4Tu"}},
+		{"Duplicate", "Ab3De5Gh7JkMn9Pq2Rs4Tu and Ab3De5Gh7JkMn9Pq2Rs4Tu", []string{"Ab3De5Gh7JkMn9Pq2Rs4Tu"}},
+		{"OtherProject", "Closes Zz3De5Gh7JkMn9Pq2Rs4Tu", []string{}},
+		{"TooLong", "Ab3De5Gh7JkMn9Pq2Rs4Tuv", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentionedTaskIDs(projectId, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mentionedTaskIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkTitle(t *testing.T) {
+	long := strings.Repeat("ä", maxTaskLinkTitleLength+10)
+
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"Short", "Fix typo", "Fix typo"},
+		{"Trimmed", "  Fix typo\r", "Fix typo"},
+		{"Exact", long[:2*maxTaskLinkTitleLength], long[:2*maxTaskLinkTitleLength]},
+		{"Overlong", long, long[:2*(maxTaskLinkTitleLength-1)] + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := linkTitle(tt.title)
+			if got != tt.want {
+				t.Errorf("linkTitle() = %q, want %q", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > maxTaskLinkTitleLength {
+				t.Errorf("linkTitle() has %d characters, want at most %d", n, maxTaskLinkTitleLength)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package src
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"dump.link/src/models"
+)
+
+// readinessTimeout bounds the database queries of the readiness check.
+const readinessTimeout = 2 * time.Second
+
+// A degraded check is reported, but does not make the instance unavailable.
+const (
+	checkOK       = "ok"
+	checkDegraded = "degraded"
+	checkFailed   = "failed"
+)
+
+// HealthGet is the liveness check. It only tells that the process serves
+// requests, a failing database does not make a restart any better.
+func (app *application) HealthGet(w http.ResponseWriter, r *http.Request) {
+	data := map[string]string{
+		"status": "available",
+	}
+
+	err := app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.logger.Error(err.Error())
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// ReadyGet is the readiness check. It responds with 503 unless the database
+// answers in time, no migration failed halfway, the hub accepts clients and no
+// scheduled job is stuck. Pending migrations only degrade it.
+func (app *application) ReadyGet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := envelope{
+		"database":   app.checkDatabase(ctx),
+		"migrations": app.checkMigrations(ctx),
+		"hub":        app.checkHub(),
+		"scheduler":  app.checkScheduler(time.Now()),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		switch check.(envelope)["status"] {
+		case checkFailed:
+			status, code = "unavailable", http.StatusServiceUnavailable
+		case checkDegraded:
+			if code == http.StatusOK {
+				status = "degraded"
+			}
+		}
+	}
+
+	err := app.writeJSON(w, code, envelope{"status": status, "checks": checks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkDatabase pings the database. The error is only logged, it may contain
+// the address of the database.
+func (app *application) checkDatabase(ctx context.Context) envelope {
+	err := app.db.PingContext(ctx)
+	if err != nil {
+		app.logger.ErrorContext(ctx, "readiness: database unreachable", "error", err)
+		return envelope{"status": checkFailed, "error": "unreachable"}
+	}
+	return envelope{"status": checkOK}
+}
+
+// checkMigrations compares the applied migration with the one the models are
+// written for. The migrations run after the deployment, so a new instance
+// sees an older one until they are done. That only degrades the check, a
+// failing one would keep the deployment, and with it the migrations, from
+// ever finishing. A newer one is fine.
+func (app *application) checkMigrations(ctx context.Context) envelope {
+	version, dirty, err := app.migrations.Version(ctx)
+	if err != nil {
+		app.logger.ErrorContext(ctx, "readiness: reading the migration version", "error", err)
+		return envelope{"status": checkFailed, "error": "unknown version", "expected": models.SchemaVersion}
+	}
+
+	check := envelope{"status": checkOK, "version": version, "expected": models.SchemaVersion}
+	switch {
+	case dirty:
+		check["status"] = checkFailed
+		check["error"] = "migration failed halfway"
+	case version < models.SchemaVersion:
+		check["status"] = checkDegraded
+		check["error"] = "migrations pending"
+	}
+	return check
+}
+
+// checkHub fails once the hub shuts down, so that no new websockets are sent
+// to this instance.
+func (app *application) checkHub() envelope {
+	projects, clients := app.hub.stats()
+
+	check := envelope{"status": checkOK, "projects": projects, "clients": clients}
+	if app.hub.closing.Load() {
+		check["status"] = checkFailed
+		check["error"] = "shutting down"
+	}
+	return check
+}
+
+func (app *application) checkScheduler(now time.Time) envelope {
+	stale := app.scheduler.staleJobs(now)
+	if len(stale) > 0 {
+		return envelope{"status": checkFailed, "error": "jobs are not running", "stale": stale}
+	}
+	return envelope{"status": checkOK}
+}
@@ -0,0 +1,140 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReadyGetUnavailable(t *testing.T) {
+	// nothing listens on port 1, so every query fails right away.
+	db, err := sql.Open("mysql", "user:pass@tcp(127.0.0.1:1)/dumplink?timeout=100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{
+		logger:     logger,
+		db:         db,
+		migrations: &models.MigrationModel{DB: db},
+		hub:        newHub(logger, nil),
+	}
+	app.scheduler.add("cleanup", time.Minute, time.Now())
+
+	w := httptest.NewRecorder()
+	app.ReadyGet(w, httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	var body struct {
+		Status string                    `json:"status"`
+		Checks map[string]map[string]any `json:"checks"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"database":   checkFailed,
+		"migrations": checkFailed,
+		"hub":        checkOK,
+		"scheduler":  checkOK,
+	}
+	for name, status := range want {
+		if got := body.Checks[name]["status"]; got != status {
+			t.Errorf("%s status = %v, want %s", name, got, status)
+		}
+	}
+	if body.Status != "unavailable" {
+		t.Errorf("status = %q, want unavailable", body.Status)
+	}
+}
+
+func TestReadyGetMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		dirty   bool
+		code    int
+		status  string
+	}{
+		{"Applied", models.SchemaVersion, false, http.StatusOK, "ready"},
+		// old instances still run while the next deployment migrates.
+		{"Newer", models.SchemaVersion + 1, false, http.StatusOK, "ready"},
+		// new instances are deployed before the migrations run.
+		{"Pending", models.SchemaVersion - 1, false, http.StatusOK, "degraded"},
+		{"Dirty", models.SchemaVersion, true, http.StatusServiceUnavailable, "unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+				WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(tt.version, tt.dirty))
+
+			w := httptest.NewRecorder()
+			app.ReadyGet(w, httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil))
+
+			var body struct {
+				Status string `json:"status"`
+			}
+			err := json.NewDecoder(w.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.code || body.Status != tt.status {
+				t.Errorf("ReadyGet() = %d %s, want %d %s", w.Code, body.Status, tt.code, tt.status)
+			}
+		})
+	}
+}
+
+func TestCheckHub(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{logger: logger, hub: newHub(logger, nil)}
+	app.hub.register(newTestClient("abc", "t1", "Ada", 1))
+	app.hub.register(newTestClient("abc", "t2", "Grace", 1))
+
+	check := app.checkHub()
+	if check["status"] != checkOK || check["projects"] != 1 || check["clients"] != 2 {
+		t.Errorf("checkHub() = %v", check)
+	}
+
+	app.hub.shutdown()
+	if check := app.checkHub(); check["status"] != checkFailed {
+		t.Errorf("checkHub() while shutting down = %v", check)
+	}
+}
+
+func TestSchedulerStaleJobs(t *testing.T) {
+	var s scheduler
+	start := time.Now()
+
+	s.add("broker poll", 250*time.Millisecond, start)
+	s.add("appetite notifications", 5*time.Minute, start)
+
+	if got := s.staleJobs(start.Add(30 * time.Second)); len(got) != 0 {
+		t.Errorf("staleJobs() after 30s = %v, want none", got)
+	}
+
+	s.ran("appetite notifications", start.Add(10*time.Minute))
+	got := s.staleJobs(start.Add(16 * time.Minute))
+	if !reflect.DeepEqual(got, []string{"broker poll"}) {
+		t.Errorf("staleJobs() after 16m = %v, want [broker poll]", got)
+	}
+}
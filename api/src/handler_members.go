@@ -0,0 +1,252 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) ApiGetMembers(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	members, err := app.members.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if members == nil {
+		members = []*models.ProjectMember{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, members, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// ApiPostMember adds a member or changes the role of an existing one.
+// Ownership can only be changed with ApiTransferOwnership.
+func (app *application) ApiPostMember(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	var input struct {
+		Subject string `json:"subject"`
+		Role    string `json:"role"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	v.Check(validation.NotBlank(input.Subject), "subject", "must be provided")
+	v.Check(validation.MaxChars(input.Subject, validation.MaxNameLength), "subject", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	v.Check(validation.PermittedValue(input.Role, models.RoleViewer, models.RoleEditor), "role", "must be viewer or editor")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	currentRole, err := app.members.GetRole(r.Context(), projectId, input.Subject)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if currentRole == models.RoleOwner {
+		app.badRequestResponse(w, r, errors.New("the owner can only change by transferring the ownership"))
+		return
+	}
+
+	err = app.members.Upsert(r.Context(), projectId, input.Subject, input.Role, subjectFromRequest(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"projectId": projectId,
+		"subject":   input.Subject,
+		"role":      input.Role,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionUpdateMember), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) ApiDeleteMember(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+	subject := params.ByName("subject")
+
+	role, err := app.members.GetRole(r.Context(), projectId, subject)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if role == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if role == models.RoleOwner {
+		app.badRequestResponse(w, r, errors.New("the owner can not be removed"))
+		return
+	}
+
+	_, err = app.members.Delete(r.Context(), projectId, subject)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"projectId": projectId,
+		"subject":   subject,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionRemoveMember), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// ApiTransferOwnership hands the project over to another subject. Projects
+// without an owner, like the ones created before memberships existed, can
+// only be claimed by their creator, whose verified email has to match the one
+// the project was created with. Everybody else with the link is an editor of
+// those projects and could lock the team out otherwise.
+func (app *application) ApiTransferOwnership(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	subject := subjectFromRequest(r)
+	if subject == "" {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	owner, err := app.members.GetOwner(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Subject string `json:"subject"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	if v.Check(validation.NotBlank(input.Subject), "subject", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	switch {
+	case owner == subject:
+		// the owner may hand over to anybody.
+	case owner == "" && input.Subject == subject:
+		// claiming a project nobody owns.
+		creator, err := app.isProjectCreator(r, projectId)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !creator {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	default:
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	err = app.members.TransferOwnership(r.Context(), projectId, input.Subject, subject)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"projectId": projectId,
+		"owner":     input.Subject,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionTransferOwnership), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// isProjectCreator reports whether the verified email of the request is the
+// one the project was created with.
+func (app *application) isProjectCreator(r *http.Request, projectId string) (bool, error) {
+	email := verifiedEmailFromRequest(r)
+	if email == "" {
+		return false, nil
+	}
+
+	ownerEmail, err := app.projects.GetOwnerEmail(r.Context(), projectId)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(strings.TrimSpace(ownerEmail), email), nil
+}
@@ -0,0 +1,67 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApiTransferOwnershipClaim(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *tokenClaims
+		// ownerEmail is the email the project was created with, if it is looked up.
+		ownerEmail *string
+		want       int
+	}{
+		{"Creator", &tokenClaims{Username: "ada-sub", Email: "Ada@example.com"}, ptr("ada@example.com"), http.StatusOK},
+		{"OtherEditor", &tokenClaims{Username: "mallory-sub", Email: "mallory@example.com"}, ptr("ada@example.com"), http.StatusForbidden},
+		{"UnverifiedEmail", &tokenClaims{Username: "mallory-sub"}, nil, http.StatusForbidden},
+		{"NoOwnerEmail", &tokenClaims{Username: "mallory-sub", Email: "mallory@example.com"}, ptr(""), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			expectExists(mock, "projects", true)
+			mock.ExpectQuery(`SELECT subject FROM project_members WHERE project_id = \? AND role = \?`).
+				WithArgs(testProjectID, models.RoleOwner).
+				WillReturnRows(sqlmock.NewRows([]string{"subject"}))
+			if tt.ownerEmail != nil {
+				mock.ExpectQuery(`SELECT owner_email FROM projects WHERE id = \?`).
+					WithArgs(testProjectID).
+					WillReturnRows(sqlmock.NewRows([]string{"owner_email"}).AddRow(*tt.ownerEmail))
+			}
+			if tt.want == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE project_members SET role = \?`).
+					WithArgs(models.RoleEditor, testProjectID, models.RoleOwner).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`INSERT INTO project_members`).
+					WithArgs(testProjectID, tt.claims.Username, models.RoleOwner, tt.claims.Username).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				expectAction(mock, ActionTransferOwnership)
+			}
+
+			body := `{"subject":"` + tt.claims.Username + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/projects/"+testProjectID+"/transfer", strings.NewReader(body))
+			r.Header.Set("Username", "Ada")
+			r = withClaims(r, tt.claims)
+			w := serveRoute("/api/v1/projects/:projectId/transfer", app.ApiTransferOwnership, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,152 @@
+package src
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+)
+
+// ApiGetNotificationTargets lists the webhooks of the project, with their
+// full URLs. Only the owner may see them.
+func (app *application) ApiGetNotificationTargets(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	targets, err := app.notificationTargets.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if targets == nil {
+		targets = []*models.NotificationTarget{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, targets, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) ApiPostNotificationTarget(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	project, err := app.projects.Get(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if project.Archived {
+		app.goneResponse(w, r)
+		return
+	}
+
+	var input struct {
+		URL     string   `json:"url"`
+		Actions []string `json:"actions"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	webhookURL, err := url.Parse(input.URL)
+	v.Check(err == nil && (webhookURL.Scheme == "http" || webhookURL.Scheme == "https") && webhookURL.Host != "", "url", "must be an absolute http or https URL")
+	v.Check(len(input.Actions) > 0, "actions", "at least one action is required")
+	for _, action := range input.Actions {
+		v.Check(notifiableActions[ActionType(action)], "actions", fmt.Sprintf("action %q can not trigger notifications", action))
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	targetId, err := app.notificationTargets.Insert(r.Context(), projectId, input.URL, input.Actions, username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	target, err := app.notificationTargets.Get(r.Context(), targetId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, target, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionAddNotificationTarget), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) ApiDeleteNotificationTarget(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	targetId, valid := app.getAndValidateID(w, r, "targetId")
+	if !valid {
+		return
+	}
+
+	target, err := app.notificationTargets.Get(r.Context(), targetId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if target.ProjectID != projectId {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.notificationTargets.Delete(r.Context(), targetId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"id": targetId,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionRemoveNotificationTarget), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+)
+
+func TestNotificationTargetsNeedOwner(t *testing.T) {
+	targetId := testProjectID + "Tg5Rt7Wb9Hk"
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/notifications"},
+		{http.MethodPost, "/notifications"},
+		{http.MethodDelete, "/notifications/" + targetId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			app, mock := newTestApp(t)
+			app.config.Features.Notifications = true
+
+			// everybody with the link may edit the project.
+			expectExists(mock, "projects", true)
+			expectProject(mock, testProjectID, models.RoleEditor)
+
+			r := httptest.NewRequest(tt.method, "/api/v1/projects/"+testProjectID+tt.path, strings.NewReader(`{"url":"https://hooks.slack.com/services/T0/B0/x"}`))
+			r.Header.Set("Username", "Mallory")
+			w := httptest.NewRecorder()
+			app.routes().ServeHTTP(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,12 +1,12 @@
 package src
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"dump.link/src/models"
+	"dump.link/src/validation"
 )
 
 func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
@@ -23,13 +23,13 @@ func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	buckets, err := app.buckets.GetForProjectId(projectId)
+	buckets, err := app.buckets.GetForProjectId(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -38,7 +38,7 @@ func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
 		buckets = []*models.Bucket{}
 	}
 
-	tasks, err := app.tasks.GetForProjectId(projectId)
+	tasks, err := app.tasks.GetForProjectId(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -47,7 +47,19 @@ func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
 		tasks = []*models.Task{}
 	}
 
-	dependencies, err := app.dependencies.GetForProjectId(projectId)
+	assignees, err := app.taskAssignees.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, task := range tasks {
+		task.Assignees = assignees[task.ID]
+		if task.Assignees == nil {
+			task.Assignees = []string{}
+		}
+	}
+
+	dependencies, err := app.dependencies.GetForProjectId(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -56,21 +68,36 @@ func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
 		dependencies = []*models.Dependency{}
 	}
 
-	activities, err := app.activities.GetForProjectId(projectId)
+	links, err := app.taskLinks.GetForProjectId(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	if activities == nil {
-		activities = []*models.Activity{}
+	if links == nil {
+		links = []*models.TaskLink{}
+	}
+
+	commentCounts, err := app.comments.CountForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	activities, err := app.projectActivities(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	data := envelope{
-		"project":      project,
-		"buckets":      buckets,
-		"tasks":        tasks,
-		"dependencies": dependencies,
-		"activities":   activities,
+		"project":       project,
+		"buckets":       buckets,
+		"tasks":         tasks,
+		"dependencies":  dependencies,
+		"links":         links,
+		"commentCounts": commentCounts,
+		"activities":    activities,
+		"role":          app.contextGetProjectRole(r),
 	}
 
 	err = app.writeJSON(w, http.StatusOK, data, nil)
@@ -79,7 +106,16 @@ func (app *application) ApiProjectGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.actions.Insert(projectId, nil, nil, startTime, string(ActionSetInitialState), username)
+	// the dashboard lists the projects a subject recently opened.
+	if subject := subjectFromRequest(r); subject != "" {
+		err = app.projectVisits.Record(r.Context(), projectId, subject)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionSetInitialState), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -101,11 +137,13 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 	}
 
 	var input struct {
-		Name      *string `json:"name,omitempty"`
-		StartedAt *string `json:"startedAt,omitempty"`
-		EndingAt  *string `json:"endingAt,omitempty"`
-		Appetite  *int    `json:"appetite,omitempty"`
-		Archived  *bool   `json:"archived,omitempty"`
+		Name            *string `json:"name,omitempty"`
+		StartedAt       *string `json:"startedAt,omitempty"`
+		EndingAt        *string `json:"endingAt,omitempty"`
+		Appetite        *int    `json:"appetite,omitempty"`
+		Archived        *bool   `json:"archived,omitempty"`
+		AnonymousAccess *string `json:"anonymousAccess,omitempty"`
+		appFields
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -114,6 +152,33 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	v := validation.New()
+	if input.Name != nil {
+		v.Check(validation.NotBlank(*input.Name), "name", "must be provided")
+		v.Check(validation.MaxChars(*input.Name, validation.MaxNameLength), "name", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	}
+
+	var startedAt, endingAt time.Time
+	if input.StartedAt != nil {
+		startedAt, err = time.Parse("2006-01-02", *input.StartedAt)
+		v.Check(err == nil, "startedAt", "must be a date like 2006-01-02")
+	}
+	if input.EndingAt != nil {
+		endingAt, err = time.Parse("2006-01-02", *input.EndingAt)
+		v.Check(err == nil, "endingAt", "must be a date like 2006-01-02")
+	}
+	if input.Appetite != nil {
+		v.Check(*input.Appetite >= 0, "appetite", "must not be negative")
+	}
+	if input.AnonymousAccess != nil {
+		v.Check(validation.PermittedValue(*input.AnonymousAccess, models.RoleNone, models.RoleViewer, models.RoleEditor), "anonymousAccess", "must be none, viewer or editor")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	data := make(map[string]interface{})
 
 	if input.Name != nil {
@@ -121,19 +186,9 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 	}
 
 	if input.StartedAt != nil {
-		startedAt, err := time.Parse("2006-01-02", *input.StartedAt)
-		if err != nil {
-			app.badRequestResponse(w, r, fmt.Errorf("invalid date format for startedAt"))
-			return
-		}
 		data["started_at"] = startedAt
 	}
 	if input.EndingAt != nil {
-		endingAt, err := time.Parse("2006-01-02", *input.EndingAt)
-		if err != nil {
-			app.badRequestResponse(w, r, fmt.Errorf("invalid date format for endingAt"))
-			return
-		}
 		data["ending_at"] = endingAt
 	}
 
@@ -145,6 +200,14 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 		data["archived"] = *input.Archived
 	}
 
+	if input.AnonymousAccess != nil {
+		if app.contextGetProjectRole(r) != models.RoleOwner {
+			app.forbiddenResponse(w, r)
+			return
+		}
+		data["anonymous_access"] = *input.AnonymousAccess
+	}
+
 	if len(data) == 0 {
 		app.badRequestResponse(w, r, fmt.Errorf("no updates provided"))
 		return
@@ -152,12 +215,19 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 
 	data["updated_by"] = username
 
-	err = app.projects.Update(projectId, data)
+	// a new time box has to warn about its appetite again.
+	if input.StartedAt != nil || input.EndingAt != nil || input.Appetite != nil {
+		data["appetite_notified"] = false
+	}
+
+	err = app.projects.Update(r.Context(), projectId, data)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	delete(data, "appetite_notified")
+
 	if data["started_at"] != nil {
 		data["startedAt"] = data["started_at"]
 		delete(data, "started_at")
@@ -168,13 +238,19 @@ func (app *application) ApiProjectPatch(w http.ResponseWriter, r *http.Request)
 		delete(data, "updated_by")
 	}
 
+	if data["anonymous_access"] != nil {
+		data["anonymousAccess"] = data["anonymous_access"]
+		delete(data, "anonymous_access")
+	}
+
 	data["id"] = projectId
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionUpdateProject, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionUpdateProject, data)
+	app.notify(r.Context(), projectId, ActionUpdateProject, data, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	err = app.actions.Insert(projectId, nil, nil, startTime, string(ActionUpdateProject), username)
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionUpdateProject), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -198,28 +274,55 @@ func (app *application) ApiProjectsPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if input.Name == "" || input.OwnerEmail == "" || input.OwnerFirstName == "" || input.OwnerLastName == "" {
-		app.badRequestResponse(w, r, errors.New("missing required fields"))
+	v := validation.New()
+	v.Check(validation.NotBlank(input.Name), "name", "must be provided")
+	v.Check(validation.MaxChars(input.Name, validation.MaxNameLength), "name", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	v.Check(input.Appetite >= 0, "appetite", "must not be negative")
+	v.Check(validation.NotBlank(input.OwnerEmail), "ownerEmail", "must be provided")
+	v.Check(validation.MaxChars(input.OwnerEmail, validation.MaxNameLength), "ownerEmail", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	v.Check(validation.NotBlank(input.OwnerFirstName), "ownerFirstName", "must be provided")
+	v.Check(validation.MaxChars(input.OwnerFirstName, validation.MaxNameLength), "ownerFirstName", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	v.Check(validation.NotBlank(input.OwnerLastName), "ownerLastName", "must be provided")
+	v.Check(validation.MaxChars(input.OwnerLastName, validation.MaxNameLength), "ownerLastName", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	projectId, err := app.projects.Insert(input.Name, input.Appetite, input.OwnerEmail, input.OwnerFirstName, input.OwnerLastName, "")
+	// the creator owns the project, if logged in, and shares it explicitly.
+	// Projects created without login can only be shared by the link.
+	subject := subjectFromRequest(r)
+	anonymousAccess := models.RoleEditor
+	if subject != "" {
+		anonymousAccess = models.RoleNone
+	}
+
+	projectId, err := app.projects.Insert(r.Context(), input.Name, input.Appetite, input.OwnerEmail, input.OwnerFirstName, input.OwnerLastName, "", anonymousAccess)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	if subject != "" {
+		err = app.members.Upsert(r.Context(), projectId, subject, models.RoleOwner, subject)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	// insert 10 buckets + 1 dump
 	for i := 0; i < 11; i++ {
 		isDump := i == 0
-		_, err := app.buckets.Insert("", false, isDump, nil, false, projectId, i)
+		_, err := app.buckets.Insert(r.Context(), "", false, isDump, nil, false, projectId, i)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -235,7 +338,7 @@ func (app *application) ApiProjectsPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.actions.Insert(projectId, nil, nil, startTime, string(ActionCreateProject), "")
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionCreateProject), "")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -256,7 +359,7 @@ func (app *application) ApiResetProjectLayers(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -267,7 +370,7 @@ func (app *application) ApiResetProjectLayers(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.buckets.ResetProjectLayers(projectId)
+	err = app.buckets.ResetProjectLayers(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -277,10 +380,11 @@ func (app *application) ApiResetProjectLayers(w http.ResponseWriter, r *http.Req
 	data["message"] = "All layers in the project have been reset successfully"
 
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionResetProjectLayers, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionResetProjectLayers, data)
+	app.notify(r.Context(), projectId, ActionResetProjectLayers, data, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	err = app.actions.Insert(projectId, nil, nil, startTime, string(ActionResetProjectLayers), username)
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionResetProjectLayers), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,59 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApiProjectsPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		// anonymousAccess is the role of everybody else with the link.
+		anonymousAccess string
+	}{
+		{"LoggedIn", "ada-sub", models.RoleNone},
+		{"Anonymous", "", models.RoleEditor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApp(t)
+
+			expectExists(mock, "projects", false)
+			mock.ExpectExec(`INSERT INTO projects`).
+				WithArgs(sqlmock.AnyArg(), "Checkout", sqlmock.AnyArg(), 6, "ada@example.com", "Ada", "Lovelace", "", tt.anonymousAccess).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			if tt.subject != "" {
+				mock.ExpectExec(`INSERT INTO project_members`).
+					WithArgs(sqlmock.AnyArg(), tt.subject, models.RoleOwner, tt.subject).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			for i := 0; i < 11; i++ {
+				expectExists(mock, "buckets", false)
+				mock.ExpectExec(`INSERT INTO buckets`).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			mock.ExpectQuery(`SELECT id, name, .* FROM projects WHERE id = \?`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "created_at", "ending_at", "updated_at", "appetite", "archived", "updated_by", "anonymous_access"}).
+					AddRow(testProjectID, "Checkout", "2024-03-01", testCreatedAt, nil, testCreatedAt, 6, false, "", tt.anonymousAccess))
+			expectAction(mock, ActionCreateProject)
+
+			body := `{"name":"Checkout","appetite":6,"ownerEmail":"ada@example.com","ownerFirstName":"Ada","ownerLastName":"Lovelace"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/projects", strings.NewReader(body))
+			if tt.subject != "" {
+				r = withSubject(r, tt.subject)
+			}
+			w := serveRoute("/api/v1/projects", app.ApiProjectsPost, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package src
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"dump.link/src/models"
+)
+
+const (
+	// InnoDB does not index words shorter than innodb_ft_min_token_size.
+	minSearchQueryLength = 3
+	maxSearchQueryLength = 255
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	maxSearchProjects = 50
+)
+
+// ApiProjectSearch searches the tasks and buckets of a single project.
+func (app *application) ApiProjectSearch(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	app.searchResponse(w, r, []string{projectId})
+}
+
+// ApiSearch searches across the projects given in the projectIds query
+// parameter, as a comma separated list. Without it, all projects the user is
+// a member of are searched.
+func (app *application) ApiSearch(w http.ResponseWriter, r *http.Request) {
+	subject := subjectFromRequest(r)
+
+	projectIds := []string{}
+	for _, id := range strings.Split(r.URL.Query().Get("projectIds"), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			projectIds = append(projectIds, id)
+		}
+	}
+
+	if len(projectIds) == 0 {
+		memberOf, err := app.members.GetProjectIdsForSubject(r.Context(), subject)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.searchResponse(w, r, memberOf)
+		return
+	}
+
+	if len(projectIds) > maxSearchProjects {
+		app.badRequestResponse(w, r, fmt.Errorf("can not search more than %d projects at once", maxSearchProjects))
+		return
+	}
+
+	// silently skip the projects the user may not see.
+	visible := []string{}
+	for _, id := range projectIds {
+		if !app.projects.IDExists(r.Context(), id) {
+			continue
+		}
+		ok, err := app.canViewProject(r.Context(), id, subject)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if ok {
+			visible = append(visible, id)
+		}
+	}
+
+	app.searchResponse(w, r, visible)
+}
+
+func (app *application) searchResponse(w http.ResponseWriter, r *http.Request, projectIds []string) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	length := utf8.RuneCountInString(query)
+	if length < minSearchQueryLength || length > maxSearchQueryLength {
+		app.badRequestResponse(w, r, fmt.Errorf("q must be between %d and %d characters long", minSearchQueryLength, maxSearchQueryLength))
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			app.badRequestResponse(w, r, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+	}
+
+	hits, err := app.search.Search(r.Context(), query, projectIds, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if hits == nil {
+		hits = []*models.SearchHit{}
+	}
+
+	for _, hit := range hits {
+		if hit.Bucket.Dump {
+			hit.Bucket.Name = "Dump"
+		}
+	}
+
+	data := envelope{
+		"query": query,
+		"hits":  hits,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,159 @@
+package src
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+)
+
+func (app *application) ApiGetShareTokens(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	tokens, err := app.shareTokens.GetForProjectId(r.Context(), projectId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if tokens == nil {
+		tokens = []*models.ShareToken{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tokens, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// ApiPostShareToken creates a read-only share link. The token is part of the
+// response only this one time.
+func (app *application) ApiPostShareToken(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	var input struct {
+		Label     string  `json:"label"`
+		ExpiresAt *string `json:"expiresAt,omitempty"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validation.New()
+	v.Check(validation.MaxChars(input.Label, validation.MaxNameLength), "label", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+
+	var expiresAt *time.Time
+	if input.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *input.ExpiresAt)
+		v.Check(err == nil, "expiresAt", "must be an RFC 3339 timestamp")
+		v.Check(err != nil || t.After(time.Now()), "expiresAt", "must be in the future")
+		t = t.UTC()
+		expiresAt = &t
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	shareId, token, err := app.shareTokens.Insert(r.Context(), projectId, input.Label, expiresAt, username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	share, err := app.shareTokens.Get(r.Context(), shareId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"share": share,
+		"token": token,
+		"url":   fmt.Sprintf("%s/a/%s?share=%s", app.config.AppURL, projectId, token),
+	}
+
+	app.writeJSON(w, http.StatusCreated, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionCreateShareToken), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// ApiDeleteShareToken revokes a share link. The websockets and event streams
+// it let in are closed on every instance.
+func (app *application) ApiDeleteShareToken(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	username, err := app.getUsernameFromHeader(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	shareId, valid := app.getAndValidateID(w, r, "shareId")
+	if !valid {
+		return
+	}
+
+	share, err := app.shareTokens.Get(r.Context(), shareId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if share.ProjectID != projectId {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.shareTokens.Delete(r.Context(), shareId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the link is gone either way, the clients it let in are closed on the
+	// next reconnect at the latest.
+	err = app.broker.Publish(r.Context(), brokerMessage{Kind: brokerEvictShare, ProjectID: projectId, Payload: []byte(shareId)})
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "evicting share link clients", "error", err, "projectId", projectId)
+	}
+
+	data := envelope{
+		"id": shareId,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, nil, startTime, string(ActionRevokeShareToken), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApiDeleteShareToken(t *testing.T) {
+	app, mock := newTestApp(t)
+
+	shareId := testProjectID + "Sh4Re6Tk8Nm"
+
+	shared := newTestClient(testProjectID, "t1", "", 1)
+	shared.shareTokenId = shareId
+	member := newTestClient(testProjectID, "t2", "Ada", 1)
+	app.hub.register(shared)
+	app.hub.register(member)
+
+	expectExists(mock, "projects", true)
+	expectExists(mock, "share_tokens", true)
+	mock.ExpectQuery(`SELECT id, project_id, .* FROM share_tokens WHERE id = \?`).
+		WithArgs(shareId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "label", "expires_at", "created_at", "created_by"}).
+			AddRow(shareId, testProjectID, "Stakeholders", nil, testCreatedAt, "ada-sub"))
+	mock.ExpectExec(`DELETE FROM share_tokens WHERE id = \?`).
+		WithArgs(shareId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAction(mock, ActionRevokeShareToken)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/v1/projects/"+testProjectID+"/shares/"+shareId, nil)
+	r.Header.Set("Username", "Ada")
+	w := serveRoute("/api/v1/projects/:projectId/shares/:shareId", app.ApiDeleteShareToken, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if _, ok := <-shared.send; ok {
+		t.Error("the client of the revoked link is still connected")
+	}
+	if got := app.hub.count(testProjectID); got != 1 {
+		t.Errorf("count() = %d, want only the member left", got)
+	}
+}
@@ -0,0 +1,111 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sseHeartbeat keeps proxies from closing idle streams.
+const sseHeartbeat = 25 * time.Second
+
+// ApiProjectEvents streams the same events as the websocket as Server-Sent
+// Events, for networks that strip websocket upgrades and for read-only
+// embeds. Reconnecting clients send the Last-Event-ID header and get the
+// events they missed, or a RESYNC event if those are no longer known.
+func (app *application) ApiProjectEvents(w http.ResponseWriter, r *http.Request) {
+	projectId, valid := app.getAndValidateID(w, r, "projectId")
+	if !valid {
+		return
+	}
+
+	if !app.checkOrigin(r) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	ip := r.RemoteAddr
+	if app.limiter != nil {
+		ip = app.limiter.clientIP(r)
+	}
+
+	client := newWSClient(nil, projectId, app.getTokenFromRequest(r), "", ip)
+	client.shareTokenId = shareTokenIDFromContext(r.Context())
+	replay, resumed := app.hub.subscribe(client, r.Header.Get("Last-Event-ID"))
+
+	app.logger.Info(fmt.Sprintf("New SSE client registered for project: %s", projectId))
+	app.logClientCount(r.Context(), projectId, "")
+
+	defer func() {
+		// the request context is canceled once the client is gone.
+		ctx := context.WithoutCancel(r.Context())
+
+		app.hub.unregister(client)
+		app.logger.Info(fmt.Sprintf("SSE client disconnected from project: %s", projectId))
+		app.logClientCount(ctx, projectId, "")
+	}()
+
+	app.streamEvents(r.Context(), w, client, replay, resumed)
+}
+
+// streamEvents writes the events queued for the client until the request is
+// done or the client is evicted.
+func (app *application) streamEvents(ctx context.Context, w http.ResponseWriter, client *wsClient, replay []hubEvent, resumed bool) {
+	rc := http.NewResponseController(w)
+	// the stream outlives any write timeout of the server.
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumed {
+		resync, _ := json.Marshal(wsEnvelope{Action: ActionResync})
+		writeSSEEvent(w, "", resync)
+	}
+	for _, event := range replay {
+		writeSSEEvent(w, app.hub.eventID(event), event.payload)
+	}
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		err := rc.Flush()
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-client.send:
+			if !ok {
+				return
+			}
+			writeSSEEvent(w, app.hub.eventID(event), event.payload)
+		case <-ticker.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		}
+	}
+}
+
+// writeSSEEvent writes a message event. Events without an ID do not change
+// the Last-Event-ID of the client.
+func writeSSEEvent(w io.Writer, id string, data []byte) {
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	io.WriteString(w, b.String())
+}
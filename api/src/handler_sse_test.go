@@ -0,0 +1,75 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{logger: logger, hub: newHub(logger, nil)}
+
+	client := newTestClient("abc", "", "", 4)
+	replay, resumed := app.hub.subscribe(client, "old-1")
+	app.hub.broadcast(context.Background(), "abc", "", []byte(`{"action":"ADD_TASK"}`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		app.streamEvents(ctx, rec, client, replay, resumed)
+		close(done)
+	}()
+
+	// evicting the client ends the stream after the queued events.
+	app.hub.unregister(client)
+	<-done
+	cancel()
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	want := "data: {\"action\":\"RESYNC\",\"data\":null}\n\n" +
+		"id: " + app.hub.epoch + "-1\ndata: {\"action\":\"ADD_TASK\"}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestWriteSSEEvent(t *testing.T) {
+	var b strings.Builder
+	writeSSEEvent(&b, "e-1", []byte("a\nb"))
+
+	if got, want := b.String(), "id: e-1\ndata: a\ndata: b\n\n"; got != want {
+		t.Errorf("writeSSEEvent() = %q, want %q", got, want)
+	}
+}
+
+func TestApiProjectEventsOrigin(t *testing.T) {
+	// the websocket upgrader refuses the same origins.
+	for _, origin := range []string{"", "https://evil.example"} {
+		app, mock := newTestApp(t)
+		app.config.AllowedOrigins = []string{"https://dump.link"}
+
+		expectExists(mock, "projects", true)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/sse/"+testProjectID, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := serveRoute("/api/v1/sse/:projectId", app.ApiProjectEvents, r)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Origin %q: status = %d, want %d", origin, w.Code, http.StatusForbidden)
+		}
+		if app.checkOrigin(r) {
+			t.Errorf("checkOrigin() with Origin %q = true, want false", origin)
+		}
+	}
+}
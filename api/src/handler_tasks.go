@@ -1,9 +1,13 @@
 package src
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
+
+	"dump.link/src/validation"
 )
 
 func (app *application) ApiPostTask(w http.ResponseWriter, r *http.Request) {
@@ -20,7 +24,7 @@ func (app *application) ApiPostTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -35,7 +39,9 @@ func (app *application) ApiPostTask(w http.ResponseWriter, r *http.Request) {
 		Id       string `json:"id"`
 		BucketID string `json:"bucketId"`
 		Title    string `json:"title"`
+		Closed   bool   `json:"closed"`
 		Priority int    `json:"priority"`
+		appFields
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -44,36 +50,44 @@ func (app *application) ApiPostTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(input.Id) != 22 {
-		app.serverErrorResponse(w, r, fmt.Errorf("id must be exactly 22 characters long"))
-		return
+	v := validation.New()
+	v.Check(validation.InProject(input.Id, projectId), "id", "must be a task ID of this project")
+	if v.Valid() {
+		v.Check(!app.tasks.IDExists(r.Context(), input.Id), "id", "already exists")
 	}
+	v.Check(validation.InProject(input.BucketID, projectId), "bucketId", "must be a bucket ID of this project")
+	if v.Valid() {
+		v.Check(app.buckets.IDExists(r.Context(), input.BucketID), "bucketId", "does not exist")
+	}
+	v.Check(validation.MaxChars(input.Title, validation.MaxNameLength), "title", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
 
-	if len(input.BucketID) != 22 {
-		app.serverErrorResponse(w, r, fmt.Errorf("bucketId must be exactly 22 characters long"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	newTaskID, err := app.tasks.Insert(input.Id, input.Title, false, input.BucketID, input.Priority, projectId, username)
+	newTaskID, err := app.tasks.Insert(r.Context(), input.Id, input.Title, input.Closed, input.BucketID, input.Priority, projectId, username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	task, err := app.tasks.Get(newTaskID)
+	task, err := app.tasks.Get(r.Context(), newTaskID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	task.Assignees = []string{}
 
 	data := task
 
 	senderToken := app.getTokenFromRequest(r)
 
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionAddTask, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionAddTask, data)
+	app.notify(r.Context(), projectId, ActionAddTask, data, username)
 
 	app.writeJSON(w, http.StatusCreated, data, nil)
-	app.actions.Insert(projectId, nil, &task.ID, startTime, string(ActionAddTask), username)
+	app.actions.Insert(r.Context(), projectId, nil, &task.ID, startTime, string(ActionAddTask), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -98,7 +112,7 @@ func (app *application) ApiDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -109,7 +123,19 @@ func (app *application) ApiDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.tasks.Delete(taskId)
+	if !app.tasks.IDExists(r.Context(), taskId) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// the notification needs the title, which is gone afterwards.
+	task, err := app.tasks.Get(r.Context(), taskId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.tasks.Delete(r.Context(), taskId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -119,10 +145,11 @@ func (app *application) ApiDeleteTask(w http.ResponseWriter, r *http.Request) {
 		"taskId": taskId,
 	}
 	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionDeleteTask, data)
+	app.sendActionDataToProjectClients(r.Context(), projectId, senderToken, ActionDeleteTask, data)
+	app.notify(r.Context(), projectId, ActionDeleteTask, envelope{"taskId": taskId, "title": task.Title, "bucketId": task.BucketID}, username)
 	app.writeJSON(w, http.StatusOK, data, nil)
 
-	app.actions.Insert(projectId, nil, &taskId, startTime, string(ActionDeleteTask), username)
+	app.actions.Insert(r.Context(), projectId, nil, &taskId, startTime, string(ActionDeleteTask), username)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -147,7 +174,7 @@ func (app *application) ApiPatchTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := app.projects.Get(projectId)
+	project, err := app.projects.Get(r.Context(), projectId)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -158,12 +185,7 @@ func (app *application) ApiPatchTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var input struct {
-		BucketID *string `json:"bucketId,omitempty"`
-		Closed   *bool   `json:"closed,omitempty"`
-		Title    *string `json:"title,omitempty"`
-		Priority *int    `json:"priority,omitempty"`
-	}
+	var input taskUpdate
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
@@ -171,12 +193,75 @@ func (app *application) ApiPatchTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := make(envelope)
-	if input.BucketID != nil {
-		if !app.buckets.IDExists(*input.BucketID) {
+	v := validation.New()
+	if validateTaskUpdate(v, projectId, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	senderToken := app.getTokenFromRequest(r)
+	data, err := app.updateTask(r.Context(), projectId, taskId, input, username, senderToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, errRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, errNoUpdates):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, data, nil)
+
+	err = app.actions.Insert(r.Context(), projectId, nil, &taskId, startTime, string(ActionUpdateTask), username)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+type taskUpdate struct {
+	BucketID  *string   `json:"bucketId,omitempty"`
+	Closed    *bool     `json:"closed,omitempty"`
+	Title     *string   `json:"title,omitempty"`
+	Notes     *string   `json:"notes,omitempty"`
+	Priority  *int      `json:"priority,omitempty"`
+	Assignees *[]string `json:"assignees,omitempty"`
+	appFields
+}
+
+// validateTaskUpdate checks the fields and normalizes the assignees.
+func validateTaskUpdate(v *validation.Validator, projectId string, input *taskUpdate) {
+	if input.BucketID != nil {
+		v.Check(validation.InProject(*input.BucketID, projectId), "bucketId", "must be a bucket ID of this project")
+	}
+	if input.Title != nil {
+		v.Check(validation.MaxChars(*input.Title, validation.MaxNameLength), "title", fmt.Sprintf("must not be more than %d characters long", validation.MaxNameLength))
+	}
+	if input.Notes != nil {
+		v.Check(validation.MaxChars(*input.Notes, maxTaskNotesLength), "notes", fmt.Sprintf("must not be more than %d characters long", maxTaskNotesLength))
+	}
+	if input.Assignees != nil {
+		assignees, err := normalizeAssignees(*input.Assignees)
+		if err != nil {
+			v.AddError("assignees", err.Error())
 			return
 		}
+		input.Assignees = &assignees
+	}
+}
+
+// updateTask stores the changes of a task and broadcasts them. Every task
+// update goes through here, no matter whether it came from a client or an
+// integration like GitHub.
+func (app *application) updateTask(ctx context.Context, projectId string, taskId string, input taskUpdate, username string, senderToken string) (envelope, error) {
+	data := make(envelope)
+	if input.BucketID != nil {
+		if !app.buckets.IDExists(ctx, *input.BucketID) {
+			return nil, errRecordNotFound
+		}
 		data["bucket_id"] = *input.BucketID
 	}
 	if input.Closed != nil {
@@ -185,21 +270,31 @@ func (app *application) ApiPatchTask(w http.ResponseWriter, r *http.Request) {
 	if input.Title != nil {
 		data["title"] = *input.Title
 	}
+	if input.Notes != nil {
+		data["notes"] = *input.Notes
+	}
 	if input.Priority != nil {
 		data["priority"] = *input.Priority
 	}
 
-	if len(data) == 0 {
-		app.badRequestResponse(w, r, fmt.Errorf("no updates provided"))
-		return
+	if len(data) == 0 && input.Assignees == nil {
+		return nil, errNoUpdates
 	}
 
 	data["updated_by"] = username
 
-	err = app.tasks.Update(taskId, data)
+	err := app.tasks.Update(ctx, taskId, data)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+		return nil, err
+	}
+
+	// assignees live in their own table.
+	if input.Assignees != nil {
+		err = app.taskAssignees.Replace(ctx, taskId, *input.Assignees, username)
+		if err != nil {
+			return nil, err
+		}
+		data["assignees"] = *input.Assignees
 	}
 
 	// fix missmatch between database column and frontend fields.
@@ -215,13 +310,8 @@ func (app *application) ApiPatchTask(w http.ResponseWriter, r *http.Request) {
 	//always send the id, ws needs it.
 	data["id"] = taskId
 
-	senderToken := app.getTokenFromRequest(r)
-	app.sendActionDataToProjectClients(projectId, senderToken, ActionUpdateTask, data)
-	app.writeJSON(w, http.StatusOK, data, nil)
+	app.sendActionDataToProjectClients(ctx, projectId, senderToken, ActionUpdateTask, data)
+	app.notify(ctx, projectId, ActionUpdateTask, data, username)
 
-	err = app.actions.Insert(projectId, nil, &taskId, startTime, string(ActionUpdateTask), username)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
+	return data, nil
 }
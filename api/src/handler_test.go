@@ -0,0 +1,157 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dump.link/src/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	testProjectID = "Ab3De5Gh7Jk"
+	testBucketID  = testProjectID + "Bc4Df6Hj8Km"
+	testTaskID    = testProjectID + "Ta5Sk7Mn9Pq"
+	testCreatedAt = "2024-03-01 12:00:00"
+)
+
+// newTestApp returns an application whose models run on a mock database. The
+// queries have to be expected in the order the handler runs them, every
+// expectation has to be met when the test ends.
+func newTestApp(t *testing.T) (*application, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &application{
+		logger: logger,
+		db:     db,
+
+		activities:          &models.ActivityModel{DB: db},
+		buckets:             &models.BucketModel{DB: db},
+		tasks:               &models.TaskModel{DB: db},
+		projects:            &models.ProjectModel{DB: db},
+		dependencies:        &models.DependencyModel{DB: db},
+		notificationTargets: &models.NotificationTargetModel{DB: db},
+		taskLinks:           &models.TaskLinkModel{DB: db},
+		comments:            &models.CommentModel{DB: db},
+		taskAssignees:       &models.TaskAssigneeModel{DB: db},
+		search:              &models.SearchModel{DB: db},
+		members:             &models.ProjectMemberModel{DB: db},
+		shareTokens:         &models.ShareTokenModel{DB: db},
+		dashboard:           &models.DashboardModel{DB: db},
+		projectVisits:       &models.ProjectVisitModel{DB: db},
+		actions:             &models.LogActionModel{DB: db},
+		logSubscriptions:    &models.LogSubscriptionModel{DB: db},
+		brokerMessages:      &models.BrokerMessageModel{DB: db},
+		migrations:          &models.MigrationModel{DB: db},
+
+		hub: newHub(logger, nil),
+	}
+	app.broker = &memoryBroker{deliver: app.deliver}
+
+	// requests carry no tokens, use withSubject instead.
+	noTokens := func(ctx context.Context, token string) (any, error) {
+		return nil, errors.New("tokens are not verified in tests")
+	}
+	app.auth = &authenticator{
+		required: jwtmiddleware.New(noTokens),
+		optional: jwtmiddleware.New(noTokens, jwtmiddleware.WithCredentialsOptional(true)),
+	}
+
+	return app, mock
+}
+
+// serveRoute runs the handler like the router does for the route, e.g.
+// /api/v1/projects/:projectId/comments/:commentId.
+func serveRoute(route string, handler http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	router := httprouter.New()
+	router.HandlerFunc(r.Method, route, handler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// withSubject returns the request as the JWT middleware passes it on for a
+// valid token of the subject.
+func withSubject(r *http.Request, subject string) *http.Request {
+	return withClaims(r, &tokenClaims{Username: subject})
+}
+
+// withClaims is withSubject for tokens with more than a subject.
+func withClaims(r *http.Request, custom *tokenClaims) *http.Request {
+	claims := &validator.ValidatedClaims{CustomClaims: custom}
+	return r.WithContext(context.WithValue(r.Context(), jwtmiddleware.ContextKey{}, claims))
+}
+
+// expectExists expects the IDExists query of the table.
+func expectExists(mock sqlmock.Sqlmock, table string, exists bool) {
+	count := 0
+	if exists {
+		count = 1
+	}
+	mock.ExpectQuery(`SELECT COUNT\(id\) FROM ` + table + ` WHERE id = \?`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func expectProject(mock sqlmock.Sqlmock, id string, anonymousAccess string) {
+	mock.ExpectQuery(`SELECT id, name, .* FROM projects WHERE id = \?`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "created_at", "ending_at", "updated_at", "appetite", "archived", "updated_by", "anonymous_access"}).
+			AddRow(id, "Checkout", "2024-03-01", testCreatedAt, nil, testCreatedAt, 0, false, "Ada", anonymousAccess))
+}
+
+func expectBucket(mock sqlmock.Sqlmock, id string, projectId string) {
+	mock.ExpectQuery(`SELECT id, name, .* FROM buckets WHERE id = \?`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "definition_of_done", "done", "dump", "layer", "flagged", "project_id", "created_at", "updated_at", "priority", "updated_by"}).
+			AddRow(id, "Payment", "", false, false, nil, false, projectId, testCreatedAt, testCreatedAt, 0, "Ada"))
+}
+
+func expectTask(mock sqlmock.Sqlmock, id string, bucketId string, title string) {
+	mock.ExpectQuery(`SELECT id, title, .* FROM tasks WHERE id = \?`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "notes", "closed", "bucket_id", "priority", "created_at", "updated_at", "updated_by"}).
+			AddRow(id, title, "", false, bucketId, 0, testCreatedAt, testCreatedAt, "Ada"))
+}
+
+// expectComment expects the comment to be read, by any ID, as new comments
+// get a random one.
+func expectComment(mock sqlmock.Sqlmock, c *models.Comment) {
+	mock.ExpectQuery(`SELECT id, project_id, .* FROM comments WHERE id = \?`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "bucket_id", "task_id", "body", "created_by", "created_at", "updated_at"}).
+			AddRow(c.ID, c.ProjectID, nullString(c.BucketID), nullString(c.TaskID), c.Body, c.CreatedBy, testCreatedAt, testCreatedAt))
+}
+
+// expectAction expects the action to be logged.
+func expectAction(mock sqlmock.Sqlmock, action ActionType) {
+	mock.ExpectExec(`INSERT INTO log_actions`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), string(action), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func nullString(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
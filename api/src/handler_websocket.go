@@ -1,9 +1,11 @@
 package src
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/julienschmidt/httprouter"
@@ -22,12 +24,34 @@ const (
 	ActionRemoveBucketDependency ActionType = "REMOVE_BUCKET_DEPENDENCY"
 	ActionUpdateActivities       ActionType = "UPDATE_ACTIVITIES"
 	ActionDeleteTask             ActionType = "DELETE_TASK"
+	ActionAddTaskLink            ActionType = "ADD_TASK_LINK"
+	ActionAddComment             ActionType = "ADD_COMMENT"
+	ActionUpdateComment          ActionType = "UPDATE_COMMENT"
+	ActionDeleteComment          ActionType = "DELETE_COMMENT"
+	ActionPresenceJoin           ActionType = "PRESENCE_JOIN"
+	ActionPresenceLeave          ActionType = "PRESENCE_LEAVE"
+	ActionPresenceRoster         ActionType = "PRESENCE_ROSTER"
+	ActionAck                    ActionType = "ACK"
+	ActionResync                 ActionType = "RESYNC"
 
 	//not for websocket
 	ActionSetInitialState ActionType = "SET_INITIAL_STATE"
 
 	// only in the backend.
-	ActionCreateProject ActionType = "CREATE_PROJECT"
+	ActionCreateProject            ActionType = "CREATE_PROJECT"
+	ActionAddNotificationTarget    ActionType = "ADD_NOTIFICATION_TARGET"
+	ActionRemoveNotificationTarget ActionType = "REMOVE_NOTIFICATION_TARGET"
+	ActionCreateGithubSecret       ActionType = "CREATE_GITHUB_SECRET"
+	ActionUpdateMember             ActionType = "UPDATE_MEMBER"
+	ActionRemoveMember             ActionType = "REMOVE_MEMBER"
+	ActionTransferOwnership        ActionType = "TRANSFER_OWNERSHIP"
+	ActionCreateShareToken         ActionType = "CREATE_SHARE_TOKEN"
+	ActionRevokeShareToken         ActionType = "REVOKE_SHARE_TOKEN"
+
+	// only for notifications, derived from the actions above.
+	ActionFlagBucket      ActionType = "FLAG_BUCKET"
+	ActionDoneBucket      ActionType = "DONE_BUCKET"
+	ActionAppetiteWarning ActionType = "APPETITE_WARNING"
 )
 
 type wsEnvelope struct {
@@ -35,18 +59,19 @@ type wsEnvelope struct {
 	Data   interface{} `json:"data"`
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		for _, allowedOrigin := range allowedOrigins {
-			if origin == allowedOrigin {
-				return true
-			}
+// checkOrigin only lets the websocket and SSE requests of the configured
+// origins pass. Requests without an Origin are refused like any other.
+func (app *application) checkOrigin(r *http.Request) bool {
+	return app.allowedOrigin(r.Header.Get("Origin"))
+}
+
+func (app *application) allowedOrigin(origin string) bool {
+	for _, allowedOrigin := range app.config.AllowedOrigins {
+		if origin == allowedOrigin {
+			return true
 		}
-		return false
-	},
+	}
+	return false
 }
 
 func (app *application) apiHandleWebSocket(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -57,6 +82,21 @@ func (app *application) apiHandleWebSocket(w http.ResponseWriter, r *http.Reques
 
 	token := app.getTokenFromRequest(r)
 	username := app.getUsernameFromRequest(r)
+	ip := r.RemoteAddr
+	if app.limiter != nil {
+		ip = app.limiter.clientIP(r)
+	}
+
+	// shutdown waits for the handler to clean up. Count it before the
+	// connection is hijacked, Shutdown only waits until then.
+	app.wg.Add(1)
+	defer app.wg.Done()
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     app.checkOrigin,
+	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -65,39 +105,52 @@ func (app *application) apiHandleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 
 	app.logger.Info(fmt.Sprintf("WebSocket connection established for project: %s", projectId))
-	app.WebSocketHandler(conn, token, projectId, username)
+	app.WebSocketHandler(r.Context(), conn, token, projectId, username, ip)
 }
 
-func (app *application) WebSocketHandler(conn *websocket.Conn, token string, projectId string, username string) {
-	client := &wsClient{
-		conn:           conn,
-		projectId:      projectId,
-		clientToken:    token,
-		clientUsername: username,
-	}
-	app.mutex.Lock()
-	if app.clients[projectId] == nil {
-		app.clients[projectId] = make(map[*wsClient]bool)
-	}
-	app.clients[projectId][client] = true
-	app.mutex.Unlock()
+// WebSocketHandler serves a client until it disconnects. Clients receive the
+// changes of the project and send commands, see handleCommand.
+func (app *application) WebSocketHandler(ctx context.Context, conn *websocket.Conn, token string, projectId string, username string, ip string) {
+	client := newWSClient(conn, projectId, token, username, ip)
+	client.shareTokenId = shareTokenIDFromContext(ctx)
+
+	// further tabs of a user do not join again.
+	joined := app.hub.register(client) && username != ""
+	go client.writePump()
 
 	app.logger.Info(fmt.Sprintf("New WebSocket client registered for project: %s", projectId))
-	app.logClientCount(projectId, username)
+	app.logClientCount(ctx, projectId, username)
+
+	app.sendRoster(ctx, client)
+	if joined {
+		app.sendActionDataToProjectClients(ctx, projectId, token, ActionPresenceJoin, app.userPresence(ctx, projectId, username))
+	}
 
 	defer func() {
-		app.mutex.Lock()
-		delete(app.clients[projectId], client)
-		if len(app.clients[projectId]) == 0 {
-			delete(app.clients, projectId)
-		}
-		app.mutex.Unlock()
+		// the request context may be canceled once the client is gone.
+		ctx := context.WithoutCancel(ctx)
+
+		left := app.hub.unregister(client) && username != ""
 		conn.Close()
 		app.logger.Info(fmt.Sprintf("WebSocket client disconnected from project: %s", projectId))
 
-		app.logClientCount(projectId, username)
+		app.logClientCount(ctx, projectId, username)
+
+		if left {
+			app.sendActionDataToProjectClients(ctx, projectId, token, ActionPresenceLeave, presence{Username: username})
+			// keep the activities for the reconnect after a restart.
+			if !app.hub.closing.Load() {
+				app.clearActivity(ctx, projectId, username)
+			}
+		}
 	}()
 
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
@@ -105,31 +158,14 @@ func (app *application) WebSocketHandler(conn *websocket.Conn, token string, pro
 			break
 		}
 
-		app.sendMessageToProjectClients(projectId, message)
-	}
-}
-
-/**
- * Underlying sending method
- */
-func (app *application) sendMessageToProjectClients(projectId string, message []byte) {
-	app.mutex.Lock()
-	defer app.mutex.Unlock()
-
-	for client := range app.clients[projectId] {
-		err := client.conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			app.logger.Info(fmt.Sprintf("Error sending message to WebSocket client: %v", err))
-			client.conn.Close()
-			delete(app.clients[projectId], client)
-		}
+		app.handleCommand(ctx, client, message)
 	}
 }
 
 /**
  * Abstracted version, so we can send any data to any project.
  */
-func (app *application) sendActionDataToProjectClients(projectId string, senderToken string, action ActionType, data interface{}) {
+func (app *application) sendActionDataToProjectClients(ctx context.Context, projectId string, senderToken string, action ActionType, data interface{}) {
 	wsData := wsEnvelope{
 		Action: action,
 		Data:   data,
@@ -141,29 +177,19 @@ func (app *application) sendActionDataToProjectClients(projectId string, senderT
 		return
 	}
 
-	app.mutex.Lock()
-	defer app.mutex.Unlock()
+	app.metrics.countAction(action)
 
-	for client := range app.clients[projectId] {
-		if client.clientToken != senderToken {
-			err := client.conn.WriteMessage(websocket.TextMessage, messageJSON)
-			if err != nil {
-				app.logger.Info(fmt.Sprintf("Error sending message to WebSocket client: %v", err))
-				client.conn.Close()
-				delete(app.clients[projectId], client)
-			}
-		}
+	err = app.broker.Publish(ctx, brokerMessage{ProjectID: projectId, SenderToken: senderToken, Payload: messageJSON})
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error publishing WebSocket message: %v", err))
 	}
 }
 
-func (app *application) logClientCount(projectId string, username string) {
-	app.mutex.Lock()
-	defer app.mutex.Unlock()
-
-	count := len(app.clients[projectId])
+func (app *application) logClientCount(ctx context.Context, projectId string, username string) {
+	count := app.hub.count(projectId)
 
 	app.logger.Info(fmt.Sprintf("Number of WebSocket clients for project '%s': %d", projectId, count))
-	err := app.logSubscriptions.Insert(projectId, count, username)
+	err := app.logSubscriptions.Insert(ctx, projectId, count, username)
 	if err != nil {
 		app.logger.Info(fmt.Sprintf("Error logging WebSocket client count: %v", err))
 	}
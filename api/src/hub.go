@@ -0,0 +1,467 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the client.
+	writeWait = 10 * time.Second
+	// pongWait is the time allowed to read the next pong from the client.
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait.
+	pingPeriod = (pongWait * 9) / 10
+	// maxMessageSize caps the messages a client may send.
+	maxMessageSize = 64 << 10
+	// sendQueueSize is how many messages may wait for a slow client before it
+	// is evicted.
+	sendQueueSize = 256
+	// eventHistorySize is how many events of a project are kept for clients
+	// resuming a stream.
+	eventHistorySize = 256
+	// eventRetention is how long the events of a project without clients
+	// are kept.
+	eventRetention = 5 * time.Minute
+)
+
+// hubEvent is a message broadcast to a project. IDs are unique per process
+// and increase with every broadcast.
+type hubEvent struct {
+	id      uint64
+	payload []byte
+}
+
+type wsClient struct {
+	conn           *websocket.Conn
+	projectId      string
+	clientToken    string
+	clientUsername string
+	// ip is the one of the websocket request, for the rate limit of the
+	// commands the client sends.
+	ip string
+	// shareTokenId is the share token the client joined with, if it was
+	// what let it in.
+	shareTokenId string
+
+	// send is the queue of the writer goroutine. The hub closes it when the
+	// client is removed.
+	send chan hubEvent
+	// closeFrame is sent once the queue is closed. Without one, the client
+	// was evicted for being too slow.
+	closeFrame []byte
+}
+
+func newWSClient(conn *websocket.Conn, projectId string, token string, username string, ip string) *wsClient {
+	return &wsClient{
+		conn:           conn,
+		projectId:      projectId,
+		clientToken:    token,
+		clientUsername: username,
+		ip:             ip,
+		send:           make(chan hubEvent, sendQueueSize),
+	}
+}
+
+// writePump writes the queued messages and pings to the connection. It is
+// the only goroutine writing to it and closes it when the queue is closed.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case event, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				closeFrame := c.closeFrame
+				if closeFrame == nil {
+					closeFrame = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow, reconnect")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeFrame)
+				return
+			}
+
+			err := c.conn.WriteMessage(websocket.TextMessage, event.payload)
+			if err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// projectRoom holds the clients and the recent events of a project. Every
+// project has its own lock, so broadcasts to busy projects do not hold up the
+// others.
+type projectRoom struct {
+	mutex   sync.Mutex
+	clients map[*wsClient]bool
+
+	// events can be resumed after any ID since.
+	since     uint64
+	events    []hubEvent
+	lastEvent time.Time
+}
+
+// hub keeps track of the websocket and SSE clients. Broadcasting never
+// blocks: every client has a bounded queue and is evicted when it overflows.
+type hub struct {
+	logger  *slog.Logger
+	metrics *metrics
+	// epoch tells the event IDs of this process apart from earlier ones.
+	epoch   string
+	lastID  atomic.Uint64
+	closing atomic.Bool
+
+	mutex sync.Mutex
+	rooms map[string]*projectRoom
+}
+
+func newHub(logger *slog.Logger, metrics *metrics) *hub {
+	return &hub{
+		logger:  logger,
+		metrics: metrics,
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		rooms:   make(map[string]*projectRoom),
+	}
+}
+
+func (h *hub) room(projectId string) *projectRoom {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.rooms[projectId]
+}
+
+// lockRoom returns the locked room of the project, creating it if needed.
+func (h *hub) lockRoom(projectId string) *projectRoom {
+	h.mutex.Lock()
+	room, ok := h.rooms[projectId]
+	if !ok {
+		room = &projectRoom{clients: make(map[*wsClient]bool), since: h.lastID.Load()}
+		h.rooms[projectId] = room
+	}
+	// lock the room before the hub lets go, so that it can not be removed
+	// as empty in between.
+	room.mutex.Lock()
+	h.mutex.Unlock()
+
+	return room
+}
+
+// register adds the client and reports whether it is the first one of its
+// user in the project.
+func (h *hub) register(client *wsClient) bool {
+	if h.closing.Load() {
+		h.reject(client)
+		return false
+	}
+
+	room := h.lockRoom(client.projectId)
+	defer room.mutex.Unlock()
+
+	room.clients[client] = true
+	h.metrics.setConnections(client.projectId, len(room.clients))
+	return room.countUser(client.clientUsername) == 1
+}
+
+// unregister removes the client and closes its queue. It reports whether
+// no client of its user is left in the project. Removing a client twice is
+// safe, so an evicted client still reports its user leaving on disconnect.
+func (h *hub) unregister(client *wsClient) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	room, ok := h.rooms[client.projectId]
+	if !ok {
+		return true
+	}
+
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	if !room.clients[client] {
+		return room.countUser(client.clientUsername) == 0
+	}
+
+	delete(room.clients, client)
+	close(client.send)
+	h.metrics.setConnections(client.projectId, len(room.clients))
+	// rooms with events are kept for resuming clients until cleanup.
+	if len(room.clients) == 0 && len(room.events) == 0 {
+		delete(h.rooms, client.projectId)
+	}
+
+	return room.countUser(client.clientUsername) == 0
+}
+
+// countUser counts the clients of the username. The caller must hold the
+// room's lock.
+func (r *projectRoom) countUser(username string) int {
+	count := 0
+	for client := range r.clients {
+		if client.clientUsername == username {
+			count++
+		}
+	}
+	return count
+}
+
+// broadcast records the message as the project's next event and queues it
+// for every client of the project except the sender. Clients whose queue is
+// full are evicted.
+func (h *hub) broadcast(ctx context.Context, projectId string, senderToken string, message []byte) {
+	_, span := tracer.Start(ctx, "hub.broadcast", trace.WithAttributes(projectAttr(projectId)))
+	defer span.End()
+
+	var evicted []*wsClient
+	start := time.Now()
+
+	room := h.lockRoom(projectId)
+
+	event := hubEvent{id: h.lastID.Add(1), payload: message}
+	if len(room.events) == eventHistorySize {
+		room.since = room.events[0].id
+		room.events = append(room.events[:0], room.events[1:]...)
+	}
+	room.events = append(room.events, event)
+	room.lastEvent = time.Now()
+
+	clients := len(room.clients)
+	for client := range room.clients {
+		if senderToken != "" && client.clientToken == senderToken {
+			continue
+		}
+
+		select {
+		case client.send <- event:
+		default:
+			evicted = append(evicted, client)
+		}
+	}
+	room.mutex.Unlock()
+
+	h.metrics.observeBroadcast(time.Since(start))
+	span.SetAttributes(
+		attribute.Int("clients", clients),
+		attribute.Int("evicted", len(evicted)),
+	)
+
+	for _, client := range evicted {
+		h.metrics.dropMessage()
+		h.logger.Info("evicting slow WebSocket client", "projectId", projectId, "username", client.clientUsername)
+		h.unregister(client)
+	}
+}
+
+// send queues the message for a single client.
+func (h *hub) send(client *wsClient, message []byte) {
+	room := h.room(client.projectId)
+	if room == nil {
+		return
+	}
+
+	room.mutex.Lock()
+	if !room.clients[client] {
+		room.mutex.Unlock()
+		return
+	}
+
+	select {
+	case client.send <- hubEvent{payload: message}:
+		room.mutex.Unlock()
+	default:
+		room.mutex.Unlock()
+		h.metrics.dropMessage()
+		h.logger.Info("evicting slow WebSocket client", "projectId", client.projectId, "username", client.clientUsername)
+		h.unregister(client)
+	}
+}
+
+// count returns the number of clients of the project.
+func (h *hub) count(projectId string) int {
+	room := h.room(projectId)
+	if room == nil {
+		return 0
+	}
+
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	return len(room.clients)
+}
+
+// stats returns the number of projects with clients and of all clients.
+func (h *hub) stats() (projects int, clients int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, room := range h.rooms {
+		room.mutex.Lock()
+		if len(room.clients) > 0 {
+			projects++
+			clients += len(room.clients)
+		}
+		room.mutex.Unlock()
+	}
+	return projects, clients
+}
+
+// usernames returns the distinct, non-empty usernames of the project's
+// clients, sorted.
+func (h *hub) usernames(projectId string) []string {
+	usernames := []string{}
+
+	room := h.room(projectId)
+	if room == nil {
+		return usernames
+	}
+
+	room.mutex.Lock()
+	seen := make(map[string]bool)
+	for client := range room.clients {
+		if client.clientUsername != "" && !seen[client.clientUsername] {
+			seen[client.clientUsername] = true
+			usernames = append(usernames, client.clientUsername)
+		}
+	}
+	room.mutex.Unlock()
+
+	sort.Strings(usernames)
+	return usernames
+}
+
+// subscribe registers the client like register and returns the events after
+// the given one, read at the same time so that none is missed or queued
+// twice. It reports false if that event is unknown, e.g. from before a
+// restart or too old to be kept, and the client has to reload.
+func (h *hub) subscribe(client *wsClient, lastEventID string) ([]hubEvent, bool) {
+	if h.closing.Load() {
+		h.reject(client)
+		return nil, true
+	}
+
+	room := h.lockRoom(client.projectId)
+	defer room.mutex.Unlock()
+
+	room.clients[client] = true
+	h.metrics.setConnections(client.projectId, len(room.clients))
+
+	if lastEventID == "" {
+		return nil, true
+	}
+
+	epoch, seq, found := strings.Cut(lastEventID, "-")
+	id, err := strconv.ParseUint(seq, 10, 64)
+	if !found || err != nil || epoch != h.epoch || id < room.since || id > h.lastID.Load() {
+		return nil, false
+	}
+
+	replay := []hubEvent{}
+	for _, event := range room.events {
+		if event.id > id {
+			replay = append(replay, event)
+		}
+	}
+	return replay, true
+}
+
+// eventID is the ID of the event as sent to SSE clients.
+func (h *hub) eventID(event hubEvent) string {
+	return fmt.Sprintf("%s-%d", h.epoch, event.id)
+}
+
+// cleanup removes the rooms without clients whose events are too old to be
+// resumed.
+func (h *hub) cleanup() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for projectId, room := range h.rooms {
+		room.mutex.Lock()
+		if len(room.clients) == 0 && time.Since(room.lastEvent) > eventRetention {
+			delete(h.rooms, projectId)
+		}
+		room.mutex.Unlock()
+	}
+}
+
+// restartCloseFrame asks websocket clients to reconnect to the next server.
+var restartCloseFrame = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting, reconnect")
+
+// shutdown closes the queues of all clients, so that websockets get a close
+// frame and event streams end. Clients connecting afterwards are closed
+// right away.
+func (h *hub) shutdown() {
+	h.closing.Store(true)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for projectId, room := range h.rooms {
+		room.mutex.Lock()
+		for client := range room.clients {
+			client.closeFrame = restartCloseFrame
+			close(client.send)
+		}
+		room.clients = make(map[*wsClient]bool)
+		room.mutex.Unlock()
+		h.metrics.setConnections(projectId, 0)
+
+		delete(h.rooms, projectId)
+	}
+}
+
+// revokedCloseFrame tells clients that the share link they joined with is
+// gone.
+var revokedCloseFrame = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "share link revoked")
+
+// evictShareToken closes the clients of the project that joined with the
+// share token. It returns how many there were.
+func (h *hub) evictShareToken(projectId string, shareTokenId string) int {
+	room := h.room(projectId)
+	if room == nil {
+		return 0
+	}
+
+	var evicted []*wsClient
+	room.mutex.Lock()
+	for client := range room.clients {
+		if client.shareTokenId == shareTokenId {
+			client.closeFrame = revokedCloseFrame
+			evicted = append(evicted, client)
+		}
+	}
+	room.mutex.Unlock()
+
+	for _, client := range evicted {
+		h.unregister(client)
+	}
+	return len(evicted)
+}
+
+// reject closes the queue of a client connecting during shutdown.
+func (h *hub) reject(client *wsClient) {
+	client.closeFrame = restartCloseFrame
+	close(client.send)
+}
@@ -0,0 +1,190 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(projectId string, token string, username string, queue int) *wsClient {
+	return &wsClient{
+		projectId:      projectId,
+		clientToken:    token,
+		clientUsername: username,
+		send:           make(chan hubEvent, queue),
+	}
+}
+
+func TestHubRegister(t *testing.T) {
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	first := newTestClient("abc", "t1", "Ada", 1)
+	second := newTestClient("abc", "t2", "Ada", 1)
+	other := newTestClient("abc", "t3", "Grace", 1)
+
+	if !h.register(first) {
+		t.Error("register(first tab) = false, want true")
+	}
+	if h.register(second) {
+		t.Error("register(second tab) = true, want false")
+	}
+	h.register(other)
+
+	if got := h.count("abc"); got != 3 {
+		t.Errorf("count() = %d, want 3", got)
+	}
+	if got := h.usernames("abc"); !reflect.DeepEqual(got, []string{"Ada", "Grace"}) {
+		t.Errorf("usernames() = %v, want [Ada Grace]", got)
+	}
+
+	if h.unregister(first) {
+		t.Error("unregister(first tab) = true, want false")
+	}
+	if !h.unregister(second) {
+		t.Error("unregister(last tab) = false, want true")
+	}
+	if !h.unregister(second) {
+		t.Error("unregister() twice = false, want true")
+	}
+
+	h.unregister(other)
+	if _, ok := h.rooms["abc"]; ok {
+		t.Error("empty room was not removed")
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	sender := newTestClient("abc", "t1", "Ada", 1)
+	receiver := newTestClient("abc", "t2", "Grace", 1)
+	slow := newTestClient("abc", "t3", "Linus", 0)
+	elsewhere := newTestClient("xyz", "t4", "Ada", 1)
+	for _, client := range []*wsClient{sender, receiver, slow, elsewhere} {
+		h.register(client)
+	}
+
+	h.broadcast(context.Background(), "abc", "t1", []byte("hello"))
+
+	if len(sender.send) != 0 {
+		t.Error("the sender received its own message")
+	}
+	if got := string((<-receiver.send).payload); got != "hello" {
+		t.Errorf("receiver got %q, want hello", got)
+	}
+	if len(elsewhere.send) != 0 {
+		t.Error("a client of another project received the message")
+	}
+
+	// the slow client's queue is full, so it is evicted and its queue closed.
+	if _, ok := <-slow.send; ok {
+		t.Error("the slow client's queue is still open")
+	}
+	if got := h.count("abc"); got != 2 {
+		t.Errorf("count() = %d, want 2", got)
+	}
+}
+
+func TestHubSubscribe(t *testing.T) {
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	h.broadcast(context.Background(), "abc", "", []byte("one"))
+	h.broadcast(context.Background(), "xyz", "", []byte("elsewhere"))
+	h.broadcast(context.Background(), "abc", "", []byte("two"))
+	h.broadcast(context.Background(), "abc", "", []byte("three"))
+
+	room := h.room("abc")
+	first := h.eventID(room.events[0])
+
+	replay, resumed := h.subscribe(newTestClient("abc", "", "", 1), first)
+	if !resumed {
+		t.Fatalf("subscribe(%s) did not resume", first)
+	}
+	got := []string{}
+	for _, event := range replay {
+		got = append(got, string(event.payload))
+	}
+	if !reflect.DeepEqual(got, []string{"two", "three"}) {
+		t.Errorf("replay = %v, want [two three]", got)
+	}
+
+	replay, resumed = h.subscribe(newTestClient("abc", "", "", 1), "")
+	if !resumed || len(replay) != 0 {
+		t.Errorf("subscribe() without an ID = %v, %v, want nothing to replay", replay, resumed)
+	}
+
+	for _, id := range []string{"old-1", "garbage", h.epoch + "-999"} {
+		if _, resumed := h.subscribe(newTestClient("abc", "", "", 1), id); resumed {
+			t.Errorf("subscribe(%s) resumed, want a resync", id)
+		}
+	}
+
+	// events that fell out of the history can not be resumed.
+	for i := 0; i < eventHistorySize; i++ {
+		h.broadcast(context.Background(), "abc", "", []byte("more"))
+	}
+	if _, resumed := h.subscribe(newTestClient("abc", "", "", 1), first); resumed {
+		t.Error("subscribe() resumed after events were dropped from the history")
+	}
+}
+
+func TestHubShutdown(t *testing.T) {
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	client := newTestClient("abc", "t1", "Ada", 1)
+	h.register(client)
+
+	h.shutdown()
+
+	if _, ok := <-client.send; ok {
+		t.Error("the client's queue is still open")
+	}
+	if string(client.closeFrame) != string(restartCloseFrame) {
+		t.Errorf("closeFrame = %q, want the restart frame", client.closeFrame)
+	}
+	if got := h.count("abc"); got != 0 {
+		t.Errorf("count() = %d, want 0", got)
+	}
+
+	// clients connecting during shutdown are closed right away.
+	late := newTestClient("abc", "t2", "Grace", 1)
+	h.register(late)
+	if _, ok := <-late.send; ok {
+		t.Error("the late client's queue is still open")
+	}
+	if !h.unregister(late) {
+		t.Error("unregister(late client) = false, want true")
+	}
+}
+
+func TestHubEvictShareToken(t *testing.T) {
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	shared := newTestClient("abc", "t1", "", 1)
+	shared.shareTokenId = "s1"
+	otherShare := newTestClient("abc", "t2", "", 1)
+	otherShare.shareTokenId = "s2"
+	member := newTestClient("abc", "t3", "Ada", 1)
+	for _, client := range []*wsClient{shared, otherShare, member} {
+		h.register(client)
+	}
+
+	if got := h.evictShareToken("abc", "s1"); got != 1 {
+		t.Errorf("evictShareToken() = %d, want 1", got)
+	}
+	if _, ok := <-shared.send; ok {
+		t.Error("the client of the revoked link is still connected")
+	}
+	if string(shared.closeFrame) != string(revokedCloseFrame) {
+		t.Errorf("closeFrame = %q, want the revoked frame", shared.closeFrame)
+	}
+	if got := h.count("abc"); got != 2 {
+		t.Errorf("count() = %d, want 2", got)
+	}
+
+	if got := h.evictShareToken("xyz", "s2"); got != 0 {
+		t.Errorf("evictShareToken() of another project = %d, want 0", got)
+	}
+}
@@ -0,0 +1,49 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"Valid", `{"title":"Login"}`, ""},
+		{"App fields", `{"title":"Login","updatedBy":"Ada","createdAt":"2024-01-01T00:00:00Z"}`, ""},
+		{"Unknown field", `{"title":"Login","color":"red"}`, `body contains unknown key "color"`},
+		{"Two values", `{"title":"Login"}{"title":"Logout"}`, "body must only contain a single JSON value"},
+		{"Empty", ``, "body must not be empty"},
+		{"Too large", `{"title":"` + strings.Repeat("a", maxRequestBodySize) + `"}`, "body must not be larger than 1048576 bytes"},
+	}
+
+	app := &application{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input struct {
+				Title string `json:"title"`
+				appFields
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			err := app.readJSON(httptest.NewRecorder(), r, &input)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("readJSON() error = %v", err)
+				}
+				if input.Title != "Login" {
+					t.Errorf("Title = %q, want %q", input.Title, "Login")
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("readJSON() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
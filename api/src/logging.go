@@ -0,0 +1,123 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"dump.link/src/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newLogger creates the logger in the configured format. Secrets and
+// personal data are redacted from every record.
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(contextLogHandler{handler})
+}
+
+// contextLogHandler adds the request ID and the IDs of the current trace and
+// span to the records logged with a context.
+type contextLogHandler struct {
+	slog.Handler
+}
+
+func (h contextLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextLogHandler) WithGroup(name string) slog.Handler {
+	return contextLogHandler{h.Handler.WithGroup(name)}
+}
+
+// secretKeys are never logged. personalKeys are logged as their MD5 hash,
+// like the created_by of log_actions, so that the records of a user can still
+// be told apart.
+var (
+	secretKeys   = []string{"token", "access_token", "share", "share_token", "authorization", "password", "secret"}
+	personalKeys = []string{"username", "email", "subject", "created_by"}
+)
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// redactAttr is the ReplaceAttr of the log handlers.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindString {
+		return a
+	}
+
+	key := strings.ToLower(a.Key)
+	value := a.Value.String()
+
+	switch {
+	case value == "":
+		return a
+	case containsKey(secretKeys, key):
+		return slog.String(a.Key, "[redacted]")
+	case containsKey(personalKeys, key):
+		return slog.String(a.Key, models.ToMD5Hash(value))
+	case key == "uri" || key == "url":
+		value = redactURI(value)
+	}
+
+	return slog.String(a.Key, emailPattern.ReplaceAllString(value, "[email]"))
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURI replaces the values of secret and personal query parameters,
+// e.g. the token and username of websocket URLs.
+func redactURI(uri string) string {
+	path, query, found := strings.Cut(uri, "?")
+	if !found {
+		return uri
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return path + "?[redacted]"
+	}
+
+	for key := range values {
+		lower := strings.ToLower(key)
+		if containsKey(secretKeys, lower) || containsKey(personalKeys, lower) {
+			values[key] = []string{"redacted"}
+		}
+	}
+
+	return path + "?" + values.Encode()
+}
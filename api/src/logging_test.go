@@ -0,0 +1,116 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dump.link/src/models"
+	"dump.link/src/validation"
+)
+
+func TestNewLoggerRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "json", slog.LevelInfo)
+
+	logger.Info("user ada@example.com connected",
+		"uri", "/api/v1/ws/abc?token=secret&username=Ada&layer=2",
+		"username", "Ada",
+		"access_token", "secret",
+		"projectId", "abc",
+	)
+
+	var record map[string]any
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("record is not JSON: %v\n%s", err, buf.String())
+	}
+
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "Ada") || strings.Contains(buf.String(), "ada@example.com") {
+		t.Errorf("record leaks a secret or personal data: %s", buf.String())
+	}
+
+	want := map[string]any{
+		"msg":          "user [email] connected",
+		"uri":          "/api/v1/ws/abc?layer=2&token=redacted&username=redacted",
+		"username":     models.ToMD5Hash("Ada"),
+		"access_token": "[redacted]",
+		"projectId":    "abc",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	app := &application{logger: newLogger(&buf, "text", slog.LevelInfo)}
+
+	handler := app.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.logger.InfoContext(r.Context(), "handling")
+		app.notFoundResponse(w, r)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"none", "", false},
+		{"from proxy", "fly-4f2a.9", true},
+		{"forged", "x\nlevel=ERROR", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get("X-Request-ID")
+			if tt.keep && id != tt.header {
+				t.Errorf("X-Request-ID = %q, want %q", id, tt.header)
+			}
+			if !tt.keep && !validation.IsID(id, 11) {
+				t.Errorf("X-Request-ID = %q, want a new ID", id)
+			}
+
+			var body struct {
+				RequestID string `json:"request_id"`
+			}
+			json.NewDecoder(w.Body).Decode(&body)
+			if body.RequestID != id {
+				t.Errorf("request_id in body = %q, want %q", body.RequestID, id)
+			}
+
+			if !strings.Contains(buf.String(), "request_id="+id) {
+				t.Errorf("log %q does not contain the request ID", buf.String())
+			}
+		})
+	}
+}
+
+func TestRedactURI(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/projects/abc":               "/api/v1/projects/abc",
+		"/a/abc?share=xyz":                   "/a/abc?share=redacted",
+		"/api/v1/search?q=tasks&Username=Ad": "/api/v1/search?Username=redacted&q=tasks",
+	}
+
+	for uri, want := range tests {
+		if got := redactURI(uri); got != want {
+			t.Errorf("redactURI(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package src
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// The limits are in characters, which take up to 4 bytes each. Notes are
+// stored as MEDIUMTEXT, the definition of done fits into TEXT, which holds
+// 65535 bytes.
+const (
+	maxTaskNotesLength        = 20000
+	maxDefinitionOfDoneLength = 5000
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// markdownPolicy strips everything that could run code in the export,
+	// notes are written by anybody who knows the link.
+	markdownPolicy = bluemonday.UGCPolicy()
+)
+
+// renderMarkdown converts the Markdown source to sanitized HTML.
+func renderMarkdown(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	err := markdown.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes())), nil
+}
@@ -0,0 +1,41 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		contains  []string
+		forbidden []string
+	}{
+		{"Emphasis", "**done** when _tested_", []string{"<strong>done</strong>", "<em>tested</em>"}, nil},
+		{"Checklist", "- [x] works", []string{"<li>", "works"}, nil},
+		{"Script", "hi <script>alert(1)</script>", []string{"hi"}, []string{"<script"}},
+		{"Handler", `<img src="x" onerror="alert(1)">`, nil, []string{"onerror"}},
+		{"JavascriptLink", "[click](javascript:alert(1))", nil, []string{"javascript:"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderMarkdown(tt.source)
+			if err != nil {
+				t.Fatalf("renderMarkdown() error = %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("renderMarkdown() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, unwanted := range tt.forbidden {
+				if strings.Contains(string(got), unwanted) {
+					t.Errorf("renderMarkdown() = %q, must not contain %q", got, unwanted)
+				}
+			}
+		})
+	}
+}
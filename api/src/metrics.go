@@ -0,0 +1,209 @@
+package src
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"database/sql"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are exposed in the Prometheus format on /metrics. They have their
+// own registry, so that every test can start from zero. All methods are safe
+// to call on nil, for applications without metrics.
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	connections       *prometheus.GaugeVec
+	broadcastDuration prometheus.Histogram
+	droppedMessages   prometheus.Counter
+	actions           *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	factory := promauto.With(registry)
+
+	return &metrics{
+		registry: registry,
+
+		requests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "dumplink_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dumplink_http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern, method and status. Websockets and event streams last as long as the connection.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		connections: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dumplink_websocket_connections",
+			Help: "Connected websocket and SSE clients by project.",
+		}, []string{"project"}),
+		broadcastDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "dumplink_broadcast_duration_seconds",
+			Help:    "Time to queue a project event for all of its clients.",
+			Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+		}),
+		droppedMessages: factory.NewCounter(prometheus.CounterOpts{
+			Name: "dumplink_websocket_dropped_messages_total",
+			Help: "Messages dropped because the queue of a slow client was full, which evicts the client.",
+		}),
+		actions: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "dumplink_actions_total",
+			Help: "Actions sent to project clients by action type.",
+		}, []string{"action"}),
+	}
+}
+
+// registerDB exposes the stats of the connection pool.
+func (m *metrics) registerDB(db *sql.DB) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "dumplink"))
+}
+
+func (m *metrics) observeRequest(route string, method string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// setConnections sets the client count of the project. Projects without
+// clients are removed, so the series do not pile up.
+func (m *metrics) setConnections(projectId string, count int) {
+	if m == nil {
+		return
+	}
+	if count == 0 {
+		m.connections.DeleteLabelValues(projectId)
+		return
+	}
+	m.connections.WithLabelValues(projectId).Set(float64(count))
+}
+
+func (m *metrics) observeBroadcast(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.broadcastDuration.Observe(duration.Seconds())
+}
+
+func (m *metrics) dropMessage() {
+	if m == nil {
+		return
+	}
+	m.droppedMessages.Inc()
+}
+
+func (m *metrics) countAction(action ActionType) {
+	if m == nil {
+		return
+	}
+	m.actions.WithLabelValues(string(action)).Inc()
+}
+
+// handler serves the metrics. With a token, requests have to send it as
+// a bearer token.
+func (m *metrics) handler(token string) http.Handler {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// routeLabel returns the route pattern of the request, e.g.
+// /api/v1/projects/:projectId, so that there is a series per route instead of
+// per project. Requests without a route are "unmatched".
+func routeLabel(router *httprouter.Router, r *http.Request) string {
+	handle, params, _ := router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return "unmatched"
+	}
+
+	segments := strings.Split(r.URL.Path, "/")
+	next := 0
+	for _, param := range params {
+		// a catch-all parameter takes the rest of the path, including the
+		// leading slash.
+		if strings.HasPrefix(param.Value, "/") {
+			rest := strings.Count(param.Value, "/")
+			segments = append(segments[:len(segments)-rest], "*"+param.Key)
+			break
+		}
+
+		for i := next; i < len(segments); i++ {
+			if segments[i] == param.Value {
+				segments[i] = ":" + param.Key
+				next = i + 1
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// statusRecorder remembers the status code written by the handler. It can
+// still be hijacked by websockets and flushed by event streams.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil && rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,130 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRouteLabel(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/", noop)
+	router.HandlerFunc(http.MethodGet, "/static/*filepath", noop)
+	router.HandlerFunc(http.MethodGet, "/a/:projectId/*any", noop)
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId", noop)
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", noop)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/", "/"},
+		{http.MethodGet, "/static/css/app.css", "/static/*filepath"},
+		{http.MethodGet, "/a/abc/dashboard/x", "/a/:projectId/*any"},
+		{http.MethodGet, "/api/v1/projects/abc", "/api/v1/projects/:projectId"},
+		{http.MethodDelete, "/api/v1/projects/abc/dependencies/b1/b1", "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId"},
+		{http.MethodPost, "/api/v1/projects/abc", "unmatched"},
+		{http.MethodGet, "/nope", "unmatched"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := routeLabel(router, r); got != tt.want {
+			t.Errorf("routeLabel(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMeasureResponseTime(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), metrics: newMetrics()}
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	router.HandlerFunc(http.MethodGet, "/api/v1/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := app.measureResponseTime(router)(router)
+
+	for _, path := range []string{"/api/v1/projects/abc", "/api/v1/projects/def", "/api/v1/ok"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(app.metrics.requests.WithLabelValues("/api/v1/projects/:projectId", "GET", "404")); got != 2 {
+		t.Errorf("requests{404} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(app.metrics.requests.WithLabelValues("/api/v1/ok", "GET", "200")); got != 1 {
+		t.Errorf("requests{200} = %v, want 1", got)
+	}
+}
+
+func TestHubMetrics(t *testing.T) {
+	m := newMetrics()
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), m)
+
+	sender := newTestClient("abc", "t1", "Ada", 1)
+	slow := newTestClient("abc", "t2", "Grace", 1)
+	h.register(sender)
+	h.register(slow)
+
+	if got := testutil.ToFloat64(m.connections.WithLabelValues("abc")); got != 2 {
+		t.Errorf("connections{abc} = %v, want 2", got)
+	}
+
+	h.broadcast(context.Background(), "abc", "t1", []byte("one"))
+	h.broadcast(context.Background(), "abc", "t1", []byte("two"))
+
+	if got := testutil.ToFloat64(m.droppedMessages); got != 1 {
+		t.Errorf("dropped messages = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.connections.WithLabelValues("abc")); got != 1 {
+		t.Errorf("connections{abc} after eviction = %v, want 1", got)
+	}
+
+	h.unregister(sender)
+	if got := testutil.CollectAndCount(m.connections); got != 0 {
+		t.Errorf("connection series without clients = %d, want 0", got)
+	}
+}
+
+func TestMetricsHandlerToken(t *testing.T) {
+	m := newMetrics()
+	m.countAction(ActionUpdateTask)
+	handler := m.handler("secret")
+
+	tests := []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("Authorization %q: status = %d, want %d", tt.header, w.Code, tt.want)
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), `dumplink_actions_total{action="UPDATE_TASK"} 1`) {
+			t.Errorf("metrics do not contain the action count:\n%s", w.Body.String())
+		}
+	}
+}
@@ -3,9 +3,34 @@ package src
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"time"
+
+	"dump.link/src/models"
+	"github.com/julienschmidt/httprouter"
 )
 
+// validRequestID accepts the IDs of proxies and clients that do not look
+// like an attempt to forge log lines.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID gives every request an ID, or keeps the X-Request-ID it came
+// with. The ID is echoed in the response and added to the log records and
+// error bodies of the request.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = models.NewID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = app.contextSetRequestID(r, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
@@ -15,7 +40,7 @@ func (app *application) logRequest(next http.Handler) http.Handler {
 			uri    = r.URL.RequestURI()
 		)
 
-		app.logger.Info("received request", "ip", ip, "proto", proto, "method", method, "uri", uri)
+		app.logger.InfoContext(r.Context(), "received request", "ip", ip, "proto", proto, "method", method, "uri", uri)
 
 		next.ServeHTTP(w, r)
 	})
@@ -34,9 +59,9 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func secureHeaders(next http.Handler) http.Handler {
+func (app *application) secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Security-Policy", "img-src 'self' https://lh3.googleusercontent.com;")
+		w.Header().Set("Content-Security-Policy", app.config.ContentSecurityPolicy)
 
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -54,15 +79,11 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 		w.Header().Add("Vary", "Origin")
 		origin := r.Header.Get("Origin")
 
-		if origin != "" {
-			for _, allowedOrigin := range allowedOrigins {
-				if origin == allowedOrigin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
-					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Upgrade, Connection, Username")
-					break
-				}
-			}
+		if origin != "" && app.allowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Upgrade, Connection, Username, Authorization, Share-Token, X-Request-ID")
+			w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-Request-ID")
 		}
 
 		// Handle preflight requests for CORS
@@ -75,13 +96,52 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) measureResponseTime(next http.Handler) http.Handler {
+// measureResponseTime records the count and latency of requests by route and
+// status.
+func (app *application) measureResponseTime(router *httprouter.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r)
+
+			duration := time.Since(start)
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := routeLabel(router, r)
+
+			app.metrics.observeRequest(route, r.Method, status, duration)
+			app.logger.InfoContext(r.Context(), "request processed", "route", route, "status", status, "duration", duration)
+		})
+	}
+}
+
+// rateLimit rejects requests once the client IP or the project has used up
+// the budget of the route class.
+func (app *application) rateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+		if app.limiter == nil || !app.limiter.config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		next.ServeHTTP(w, r)
+		class, projectId, limited := classifyRoute(r)
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := app.limiter.clientIP(r)
+		allowed, retryAfter := app.limiter.allow(class, ip, projectId, time.Now())
+		if !allowed {
+			app.logger.Info("rate limit exceeded", "ip", ip, "class", class, "projectId", projectId)
+			app.rateLimitExceededResponse(w, r, retryAfter)
+			return
+		}
 
-		duration := time.Since(start)
-		app.logger.Info("request processed", "duration", duration)
+		next.ServeHTTP(w, r)
 	})
 }
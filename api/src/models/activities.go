@@ -1,8 +1,10 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -18,43 +20,43 @@ type ActivityModel struct {
 	DB *sql.DB
 }
 
-func (m *ActivityModel) ReplaceBucketId(projectID string, bucketID string, createdBy string) error {
-	fmt.Println("REPLACEBUCKETID")
-	tx, err := m.DB.Begin()
+func (m *ActivityModel) ReplaceBucketId(ctx context.Context, projectID string, bucketID string, createdBy string) error {
+	slog.DebugContext(ctx, "replacing activity with bucket", "projectId", projectID, "bucketId", bucketID)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	delStmt := `DELETE FROM activities WHERE project_id = ? AND created_by = ?`
-	if _, err := tx.Exec(delStmt, projectID, createdBy); err != nil {
+	if _, err := tx.ExecContext(ctx, delStmt, projectID, createdBy); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	createdAt := time.Now()
 	insertStmt := `INSERT INTO activities (project_id, bucket_id, created_by, created_at) VALUES (?, ?, ?, ?)`
-	if _, err := tx.Exec(insertStmt, projectID, bucketID, createdBy, createdAt); err != nil {
+	if _, err := tx.ExecContext(ctx, insertStmt, projectID, bucketID, createdBy, createdAt); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	return tx.Commit()
 }
-func (m *ActivityModel) ReplaceTaskId(projectID string, taskID string, createdBy string) error {
-	tx, err := m.DB.Begin()
+func (m *ActivityModel) ReplaceTaskId(ctx context.Context, projectID string, taskID string, createdBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	delStmt := `DELETE FROM activities WHERE project_id = ? AND created_by = ?`
-	if _, err := tx.Exec(delStmt, projectID, createdBy); err != nil {
+	if _, err := tx.ExecContext(ctx, delStmt, projectID, createdBy); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	createdAt := time.Now()
 	insertStmt := `INSERT INTO activities (project_id, task_id, created_by, created_at) VALUES (?, ?, ?, ?)`
-	if _, err := tx.Exec(insertStmt, projectID, taskID, createdBy, createdAt); err != nil {
+	if _, err := tx.ExecContext(ctx, insertStmt, projectID, taskID, createdBy, createdAt); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -62,21 +64,21 @@ func (m *ActivityModel) ReplaceTaskId(projectID string, taskID string, createdBy
 	return tx.Commit()
 }
 
-func (m *ActivityModel) Reset(projectID string, createdBy string) error {
-	tx, err := m.DB.Begin()
+func (m *ActivityModel) Reset(ctx context.Context, projectID string, createdBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	delStmt := `DELETE FROM activities WHERE project_id = ? AND created_by = ?`
-	if _, err := tx.Exec(delStmt, projectID, createdBy); err != nil {
+	if _, err := tx.ExecContext(ctx, delStmt, projectID, createdBy); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	createdAt := time.Now()
 	insertStmt := `INSERT INTO activities (project_id, created_by, created_at) VALUES (?, ?, ?)`
-	if _, err := tx.Exec(insertStmt, projectID, createdBy, createdAt); err != nil {
+	if _, err := tx.ExecContext(ctx, insertStmt, projectID, createdBy, createdAt); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -84,9 +86,9 @@ func (m *ActivityModel) Reset(projectID string, createdBy string) error {
 	return tx.Commit()
 }
 
-func (m *ActivityModel) GetForProjectId(projectID string) ([]*Activity, error) {
+func (m *ActivityModel) GetForProjectId(ctx context.Context, projectID string) ([]*Activity, error) {
 	stmt := `SELECT project_id, bucket_id, task_id, created_by, created_at FROM activities WHERE project_id = ?`
-	rows, err := m.DB.Query(stmt, projectID)
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID)
 	if err != nil {
 		return nil, err
 	}
@@ -116,3 +118,10 @@ func (m *ActivityModel) GetForProjectId(projectID string) ([]*Activity, error) {
 
 	return activities, nil
 }
+
+// Remove deletes the activity of the user in the project.
+func (m *ActivityModel) Remove(ctx context.Context, projectID string, createdBy string) error {
+	stmt := `DELETE FROM activities WHERE project_id = ? AND created_by = ?`
+	_, err := m.DB.ExecContext(ctx, stmt, projectID, createdBy)
+	return err
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// BrokerMessage is a websocket message published by one API instance for the
+// clients of all the others.
+type BrokerMessage struct {
+	ID          int64
+	InstanceID  string
+	Kind        string
+	ProjectID   string
+	SenderToken string
+	Payload     []byte
+}
+
+// BrokerMessageModel provides the methods to interact with the `broker_messages` table.
+type BrokerMessageModel struct {
+	DB *sql.DB
+}
+
+func (m *BrokerMessageModel) Insert(ctx context.Context, message *BrokerMessage) error {
+	stmt := `INSERT INTO broker_messages (instance_id, kind, project_id, sender_token, payload) VALUES (?, ?, ?, ?, ?)`
+	_, err := m.DB.ExecContext(ctx, stmt, message.InstanceID, message.Kind, message.ProjectID, message.SenderToken, message.Payload)
+	return err
+}
+
+// LatestID returns the ID of the newest message, or 0 if there is none.
+func (m *BrokerMessageModel) LatestID(ctx context.Context) (int64, error) {
+	stmt := `SELECT COALESCE(MAX(id), 0) FROM broker_messages`
+	var id int64
+	err := m.DB.QueryRowContext(ctx, stmt).Scan(&id)
+	return id, err
+}
+
+// GetAfter returns up to limit messages newer than the ID that were published
+// by other instances, oldest first.
+func (m *BrokerMessageModel) GetAfter(ctx context.Context, id int64, instanceID string, limit int) ([]*BrokerMessage, error) {
+	stmt := `SELECT id, instance_id, kind, project_id, sender_token, payload FROM broker_messages WHERE id > ? AND instance_id <> ? ORDER BY id LIMIT ?`
+	rows, err := m.DB.QueryContext(ctx, stmt, id, instanceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*BrokerMessage{}
+	for rows.Next() {
+		message := &BrokerMessage{}
+		err = rows.Scan(&message.ID, &message.InstanceID, &message.Kind, &message.ProjectID, &message.SenderToken, &message.Payload)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// DeleteOlderThan removes the messages older than the given age.
+func (m *BrokerMessageModel) DeleteOlderThan(ctx context.Context, age time.Duration) error {
+	stmt := `DELETE FROM broker_messages WHERE created_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND`
+	_, err := m.DB.ExecContext(ctx, stmt, int(age.Seconds()))
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -8,33 +9,34 @@ import (
 )
 
 type Bucket struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Done      bool      `json:"done"`
-	Dump      bool      `json:"dump"`
-	Layer     *int      `json:"layer"`
-	Flagged   bool      `json:"flagged"`
-	ProjectID string    `json:"projectId"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Priority  int       `json:"priority"`
-	UpdatedBy string    `json:"updatedBy"`
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	DefinitionOfDone string    `json:"definitionOfDone"` // Markdown
+	Done             bool      `json:"done"`
+	Dump             bool      `json:"dump"`
+	Layer            *int      `json:"layer"`
+	Flagged          bool      `json:"flagged"`
+	ProjectID        string    `json:"projectId"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	Priority         int       `json:"priority"`
+	UpdatedBy        string    `json:"updatedBy"`
 }
 
 type BucketModel struct {
 	DB *sql.DB
 }
 
-func (m *BucketModel) Insert(name string, done bool, dump bool, layer *int, flagged bool, projectID string, priority int) (string, error) {
+func (m *BucketModel) Insert(ctx context.Context, name string, done bool, dump bool, layer *int, flagged bool, projectID string, priority int) (string, error) {
 	var id string
 	for {
 		id = NewID(projectID)
-		if !m.IDExists(id) {
+		if !m.IDExists(ctx, id) {
 			break
 		}
 	}
 	stmt := `INSERT INTO buckets (id, name, done, dump, layer, flagged, project_id, priority) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := m.DB.Exec(stmt, id, name, done, dump, layer, flagged, projectID, priority)
+	_, err := m.DB.ExecContext(ctx, stmt, id, name, done, dump, layer, flagged, projectID, priority)
 	if err != nil {
 		return "", err
 	}
@@ -42,10 +44,10 @@ func (m *BucketModel) Insert(name string, done bool, dump bool, layer *int, flag
 	return id, nil
 }
 
-func (m *BucketModel) IDExists(id string) bool {
+func (m *BucketModel) IDExists(ctx context.Context, id string) bool {
 	stmt := `SELECT COUNT(id) FROM buckets WHERE id = ?`
 	var count int
-	err := m.DB.QueryRow(stmt, id).Scan(&count)
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
 	if err != nil {
 		// Handle error. For simplicity, return true to indicate an error occurred.
 		return true
@@ -53,9 +55,37 @@ func (m *BucketModel) IDExists(id string) bool {
 	return count > 0
 }
 
-func (m *BucketModel) GetForProjectId(projectId string) ([]*Bucket, error) {
-	stmt := `SELECT id, name, done, dump, layer, flagged, project_id, created_at, updated_at, priority, updated_by FROM buckets WHERE project_id = ? ORDER BY priority`
-	rows, err := m.DB.Query(stmt, projectId)
+func (m *BucketModel) Get(ctx context.Context, id string) (*Bucket, error) {
+	stmt := `SELECT id, name, COALESCE(definition_of_done, ''), done, dump, layer, flagged, project_id, created_at, updated_at, priority, updated_by FROM buckets WHERE id = ?`
+	row := m.DB.QueryRowContext(ctx, stmt, id)
+
+	b := &Bucket{}
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(&b.ID, &b.Name, &b.DefinitionOfDone, &b.Done, &b.Dump, &b.Layer, &b.Flagged, &b.ProjectID, &createdAtStr, &updatedAtStr, &b.Priority, &b.UpdatedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Bucket with ID %s not found", id)
+		}
+		return nil, err
+	}
+
+	b.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+	}
+
+	b.UpdatedAt, err = time.Parse(DateTimeLayout, updatedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse updatedAt: %v", err)
+	}
+
+	return b, nil
+}
+
+func (m *BucketModel) GetForProjectId(ctx context.Context, projectId string) ([]*Bucket, error) {
+	stmt := `SELECT id, name, COALESCE(definition_of_done, ''), done, dump, layer, flagged, project_id, created_at, updated_at, priority, updated_by FROM buckets WHERE project_id = ? ORDER BY priority`
+	rows, err := m.DB.QueryContext(ctx, stmt, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +96,7 @@ func (m *BucketModel) GetForProjectId(projectId string) ([]*Bucket, error) {
 		var createdAtStr, updatedAtStr string
 		b := &Bucket{}
 
-		err = rows.Scan(&b.ID, &b.Name, &b.Done, &b.Dump, &b.Layer, &b.Flagged, &b.ProjectID, &createdAtStr, &updatedAtStr, &b.Priority, &b.UpdatedBy)
+		err = rows.Scan(&b.ID, &b.Name, &b.DefinitionOfDone, &b.Done, &b.Dump, &b.Layer, &b.Flagged, &b.ProjectID, &createdAtStr, &updatedAtStr, &b.Priority, &b.UpdatedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -90,7 +120,7 @@ func (m *BucketModel) GetForProjectId(projectId string) ([]*Bucket, error) {
 
 	return buckets, nil
 }
-func (m *BucketModel) Update(bucketId string, updates map[string]interface{}) error {
+func (m *BucketModel) Update(ctx context.Context, bucketId string, updates map[string]interface{}) error {
 	queryParts := []string{}
 	args := []interface{}{}
 
@@ -102,7 +132,7 @@ func (m *BucketModel) Update(bucketId string, updates map[string]interface{}) er
 	sql := fmt.Sprintf("UPDATE buckets SET %s WHERE id = ?", strings.Join(queryParts, ", "))
 	args = append(args, bucketId)
 
-	_, err := m.DB.Exec(sql, args...)
+	_, err := m.DB.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -110,14 +140,14 @@ func (m *BucketModel) Update(bucketId string, updates map[string]interface{}) er
 	return nil
 }
 
-func (m *BucketModel) ResetProjectLayers(projectId string) error {
+func (m *BucketModel) ResetProjectLayers(ctx context.Context, projectId string) error {
 	query := `UPDATE buckets SET layer = NULL WHERE project_id = ?`
-	_, err := m.DB.Exec(query, projectId)
+	_, err := m.DB.ExecContext(ctx, query, projectId)
 	return err
 }
 
-func (m *BucketModel) ResetLayer(bucketId string) error {
+func (m *BucketModel) ResetLayer(ctx context.Context, bucketId string) error {
 	query := `UPDATE buckets SET layer = NULL WHERE id = ?`
-	_, err := m.DB.Exec(query, bucketId)
+	_, err := m.DB.ExecContext(ctx, query, bucketId)
 	return err
 }
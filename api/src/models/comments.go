@@ -0,0 +1,166 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Comment belongs to either a bucket or a task.
+type Comment struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"projectId"`
+	BucketID  *string   `json:"bucketId,omitempty"`
+	TaskID    *string   `json:"taskId,omitempty"`
+	Body      string    `json:"body"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CommentModel struct {
+	DB *sql.DB
+}
+
+func (m *CommentModel) Insert(ctx context.Context, projectID string, bucketID, taskID *string, body string, createdBy string) (string, error) {
+	var id string
+	for {
+		id = NewID(projectID)
+		if !m.IDExists(ctx, id) {
+			break
+		}
+	}
+
+	stmt := `INSERT INTO comments (id, project_id, bucket_id, task_id, body, created_by) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := m.DB.ExecContext(ctx, stmt, id, projectID, bucketID, taskID, body, createdBy)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *CommentModel) IDExists(ctx context.Context, id string) bool {
+	stmt := `SELECT COUNT(id) FROM comments WHERE id = ?`
+	var count int
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
+	if err != nil {
+		// Handle error. For simplicity, return true to indicate an error occurred.
+		return true
+	}
+	return count > 0
+}
+
+func (m *CommentModel) Get(ctx context.Context, id string) (*Comment, error) {
+	stmt := `SELECT id, project_id, bucket_id, task_id, body, created_by, created_at, updated_at FROM comments WHERE id = ?`
+	row := m.DB.QueryRowContext(ctx, stmt, id)
+
+	c := &Comment{}
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(&c.ID, &c.ProjectID, &c.BucketID, &c.TaskID, &c.Body, &c.CreatedBy, &createdAtStr, &updatedAtStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Comment with ID %s not found", id)
+		}
+		return nil, err
+	}
+
+	c.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+	}
+
+	c.UpdatedAt, err = time.Parse(DateTimeLayout, updatedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse updatedAt: %v", err)
+	}
+
+	return c, nil
+}
+
+// GetForSubject returns the comments of a bucket or a task, oldest first.
+func (m *CommentModel) GetForSubject(ctx context.Context, projectID string, bucketID, taskID *string) ([]*Comment, error) {
+	stmt := `SELECT id, project_id, bucket_id, task_id, body, created_by, created_at, updated_at
+		FROM comments
+		WHERE project_id = ? AND bucket_id <=> ? AND task_id <=> ?
+		ORDER BY created_at`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID, bucketID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		var createdAtStr, updatedAtStr string
+		c := &Comment{}
+
+		err = rows.Scan(&c.ID, &c.ProjectID, &c.BucketID, &c.TaskID, &c.Body, &c.CreatedBy, &createdAtStr, &updatedAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		c.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+		}
+
+		c.UpdatedAt, err = time.Parse(DateTimeLayout, updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updatedAt: %v", err)
+		}
+
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// CountForProjectId returns the number of comments per bucket and task ID.
+func (m *CommentModel) CountForProjectId(ctx context.Context, projectID string) (map[string]int, error) {
+	stmt := `SELECT COALESCE(task_id, bucket_id), COUNT(*) FROM comments WHERE project_id = ? GROUP BY bucket_id, task_id`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var count int
+
+		err = rows.Scan(&id, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[id] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (m *CommentModel) UpdateBody(ctx context.Context, id string, body string) error {
+	stmt := `UPDATE comments SET body = ? WHERE id = ?`
+	_, err := m.DB.ExecContext(ctx, stmt, body, id)
+	return err
+}
+
+func (m *CommentModel) Delete(ctx context.Context, id string) error {
+	stmt := `DELETE FROM comments WHERE id = ?`
+	_, err := m.DB.ExecContext(ctx, stmt, id)
+	return err
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DashboardProject is a project of the dashboard with its summary stats.
+type DashboardProject struct {
+	*Project
+	// Role is the membership of the user, empty for projects only visited.
+	Role           string     `json:"role"`
+	LastVisitedAt  *time.Time `json:"lastVisitedAt"`
+	LastActivityAt *time.Time `json:"lastActivityAt"`
+	// Buckets counts the buckets with tasks, without the dump.
+	Buckets        int `json:"buckets"`
+	DoneBuckets    int `json:"doneBuckets"`
+	FlaggedBuckets int `json:"flaggedBuckets"`
+}
+
+// DashboardSortSafelist maps the sort values of the dashboard to columns.
+var DashboardSortSafelist = map[string]string{
+	"name":         "name",
+	"createdAt":    "created_at",
+	"lastActivity": "last_activity_at",
+	"lastVisited":  "last_visited_at",
+}
+
+type DashboardModel struct {
+	DB *sql.DB
+}
+
+// GetForUser lists the projects the subject is a member of and the ones it
+// recently opened, as long as everybody with the link may see them.
+func (m *DashboardModel) GetForUser(ctx context.Context, subject string, visitedSince time.Time, archived *bool, filters Filters) ([]*DashboardProject, Metadata, error) {
+	stmt := fmt.Sprintf(`SELECT `+projectColumns+`, c.role, c.last_visited_at,
+		(SELECT MAX(la.created_at) FROM log_actions la WHERE la.project_id = projects.id) AS last_activity_at,
+		(SELECT COUNT(*) FROM buckets b WHERE b.project_id = projects.id AND b.dump = false
+			AND EXISTS (SELECT 1 FROM tasks t WHERE t.bucket_id = b.id)) AS buckets,
+		(SELECT COUNT(*) FROM buckets b WHERE b.project_id = projects.id AND b.dump = false AND b.done = true
+			AND EXISTS (SELECT 1 FROM tasks t WHERE t.bucket_id = b.id)) AS done_buckets,
+		(SELECT COUNT(*) FROM buckets b WHERE b.project_id = projects.id AND b.dump = false AND b.flagged = true) AS flagged_buckets,
+		COUNT(*) OVER() AS total
+		FROM projects
+		JOIN (
+			SELECT project_id, MAX(role) AS role, MAX(last_visited_at) AS last_visited_at FROM (
+				SELECT project_id, role, NULL AS last_visited_at FROM project_members WHERE subject = ?
+				UNION ALL
+				SELECT project_id, NULL, visited_at FROM project_visits
+					WHERE subject = ? AND visited_at >= ?
+			) u GROUP BY project_id
+		) c ON c.project_id = projects.id
+		WHERE (c.role IS NOT NULL OR projects.anonymous_access <> ?)
+		AND (? IS NULL OR projects.archived = ?)
+		ORDER BY %s %s, id ASC
+		LIMIT ? OFFSET ?`, filters.sortColumn(), filters.sortDirection())
+
+	rows, err := m.DB.QueryContext(ctx, stmt, subject, subject, visitedSince.UTC().Format(DateTimeLayout), RoleNone, archived, archived, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	projects := []*DashboardProject{}
+	for rows.Next() {
+		var (
+			dp                        DashboardProject
+			role                      sql.NullString
+			lastVisited, lastActivity sql.NullString
+		)
+
+		dp.Project, err = scanProject(extendedScanner{rows, []any{&role, &lastVisited, &lastActivity, &dp.Buckets, &dp.DoneBuckets, &dp.FlaggedBuckets, &totalRecords}})
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		dp.Role = role.String
+		dp.LastVisitedAt, err = parseNullDateTime(lastVisited)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		dp.LastActivityAt, err = parseNullDateTime(lastActivity)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		projects = append(projects, &dp)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return projects, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// extendedScanner scans additional columns after the ones of scanProject.
+type extendedScanner struct {
+	row   scanner
+	extra []any
+}
+
+func (s extendedScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+func parseNullDateTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	t, err := time.Parse(DateTimeLayout, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -17,20 +18,20 @@ type DependencyModel struct {
 	DB *sql.DB
 }
 
-func (m *DependencyModel) Insert(bucketID string, dependencyId string, createdBy string) error {
+func (m *DependencyModel) Insert(ctx context.Context, bucketID string, dependencyId string, createdBy string) error {
 	stmt := `INSERT INTO dependencies (bucket_id, dependency_id, created_by) VALUES (?, ?, ?)`
-	_, err := m.DB.Exec(stmt, bucketID, dependencyId, createdBy)
+	_, err := m.DB.ExecContext(ctx, stmt, bucketID, dependencyId, createdBy)
 	return err
 }
 
-func (m *DependencyModel) Exists(bucketID, dependencyID string) (bool, error) {
+func (m *DependencyModel) Exists(ctx context.Context, bucketID, dependencyID string) (bool, error) {
 	var exists bool
 	stmt := `SELECT EXISTS(SELECT 1 FROM dependencies WHERE bucket_id = ? AND dependency_id = ?)`
-	err := m.DB.QueryRow(stmt, bucketID, dependencyID).Scan(&exists)
+	err := m.DB.QueryRowContext(ctx, stmt, bucketID, dependencyID).Scan(&exists)
 	return exists, err
 }
 
-func (m *DependencyModel) GetForProjectId(projectId string) ([]*Dependency, error) {
+func (m *DependencyModel) GetForProjectId(ctx context.Context, projectId string) ([]*Dependency, error) {
 	stmt := `SELECT bd.bucket_id, bd.dependency_id, bd.created_at, bd.created_by
          FROM dependencies bd
          WHERE bd.bucket_id IN (
@@ -38,7 +39,7 @@ func (m *DependencyModel) GetForProjectId(projectId string) ([]*Dependency, erro
              FROM buckets b
              WHERE b.project_id = ?)`
 
-	rows, err := m.DB.Query(stmt, projectId)
+	rows, err := m.DB.QueryContext(ctx, stmt, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +71,9 @@ func (m *DependencyModel) GetForProjectId(projectId string) ([]*Dependency, erro
 	return dependencies, nil
 }
 
-func (m *DependencyModel) Delete(bucketID string, dependsOnBucketID string) (int64, error) {
+func (m *DependencyModel) Delete(ctx context.Context, bucketID string, dependsOnBucketID string) (int64, error) {
 	stmt := `DELETE FROM dependencies WHERE bucket_id = ? AND dependency_id = ?`
-	result, err := m.DB.Exec(stmt, bucketID, dependsOnBucketID)
+	result, err := m.DB.ExecContext(ctx, stmt, bucketID, dependsOnBucketID)
 	if err != nil {
 		return 0, err
 	}
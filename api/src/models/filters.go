@@ -0,0 +1,62 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+// Filters paginate and sort a list. Sort is one of SortSafelist, prefixed
+// with "-" for descending order.
+type Filters struct {
+	Page         int
+	PageSize     int
+	Sort         string
+	SortSafelist map[string]string // sort value to SQL column
+}
+
+func (f Filters) sortColumn() string {
+	column, ok := f.SortSafelist[strings.TrimPrefix(f.Sort, "-")]
+	if !ok {
+		// the handler validates the sort value, this is a bug.
+		panic("unsafe sort parameter: " + f.Sort)
+	}
+	return column
+}
+
+func (f Filters) sortDirection() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// Metadata describes the page of a paginated list.
+type Metadata struct {
+	CurrentPage  int `json:"currentPage,omitempty"`
+	PageSize     int `json:"pageSize,omitempty"`
+	FirstPage    int `json:"firstPage,omitempty"`
+	LastPage     int `json:"lastPage,omitempty"`
+	TotalRecords int `json:"totalRecords"`
+}
+
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestFilters(t *testing.T) {
+	f := Filters{Page: 3, PageSize: 20, Sort: "-lastActivity", SortSafelist: DashboardSortSafelist}
+
+	if got := f.sortColumn(); got != "last_activity_at" {
+		t.Errorf("sortColumn() = %q, want %q", got, "last_activity_at")
+	}
+	if got := f.sortDirection(); got != "DESC" {
+		t.Errorf("sortDirection() = %q, want DESC", got)
+	}
+	if got := f.offset(); got != 40 {
+		t.Errorf("offset() = %d, want 40", got)
+	}
+
+	f.Sort = "name"
+	if got := f.sortDirection(); got != "ASC" {
+		t.Errorf("sortDirection() = %q, want ASC", got)
+	}
+}
+
+func TestCalculateMetadata(t *testing.T) {
+	got := calculateMetadata(41, 2, 20)
+	want := Metadata{CurrentPage: 2, PageSize: 20, FirstPage: 1, LastPage: 3, TotalRecords: 41}
+	if got != want {
+		t.Errorf("calculateMetadata() = %+v, want %+v", got, want)
+	}
+
+	if got := calculateMetadata(0, 1, 20); got != (Metadata{}) {
+		t.Errorf("calculateMetadata() = %+v, want empty metadata", got)
+	}
+}
@@ -3,7 +3,9 @@ package models
 import (
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"strings"
 )
@@ -25,7 +27,22 @@ func NewID(prefixes ...string) string {
 	return prefix + string(id)
 }
 
+// NewSecret returns a random hex encoded secret of the given number of bytes.
+func NewSecret(size int) (string, error) {
+	secret := make([]byte, size)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate secret: %v", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
 func ToMD5Hash(text string) string {
 	hash := md5.Sum([]byte(text))
 	return hex.EncodeToString(hash[:])
 }
+
+func ToSHA256Hash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
@@ -19,11 +20,11 @@ type LogActionModel struct {
 	DB *sql.DB
 }
 
-func (m *LogActionModel) Insert(projectID string, bucketID, taskID *string, startTime time.Time, action string, createdBy string) error {
+func (m *LogActionModel) Insert(ctx context.Context, projectID string, bucketID, taskID *string, startTime time.Time, action string, createdBy string) error {
 	createdBy = ToMD5Hash(createdBy)
 	duration := int(time.Since(startTime).Milliseconds())
 	stmt := `INSERT INTO log_actions (project_id, bucket_id, task_id, action, duration, created_at, created_by) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)`
-	_, err := m.DB.Exec(stmt, projectID, bucketID, taskID, action, duration, createdBy)
+	_, err := m.DB.ExecContext(ctx, stmt, projectID, bucketID, taskID, action, duration, createdBy)
 	if err != nil {
 		return err
 	}
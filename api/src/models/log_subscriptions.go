@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
@@ -19,10 +20,10 @@ type LogSubscriptionModel struct {
 }
 
 // Insert creates a new log subscription record.
-func (m *LogSubscriptionModel) Insert(projectID string, count int, createdBy string) error {
+func (m *LogSubscriptionModel) Insert(ctx context.Context, projectID string, count int, createdBy string) error {
 	createdBy = ToMD5Hash(createdBy)
 	stmt := `INSERT INTO log_subscriptions (project_id, count, created_at, created_by) VALUES (?, ?, CURRENT_TIMESTAMP, ?)`
-	_, err := m.DB.Exec(stmt, projectID, count, createdBy)
+	_, err := m.DB.ExecContext(ctx, stmt, projectID, count, createdBy)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the migration the models are written for. It has to be
+// raised with every new migration.
+const SchemaVersion = 42
+
+// MigrationModel reads the `schema_migrations` table of golang-migrate.
+type MigrationModel struct {
+	DB *sql.DB
+}
+
+// Version returns the applied migration and whether it failed halfway.
+func (m *MigrationModel) Version(ctx context.Context) (int64, bool, error) {
+	stmt := `SELECT version, dirty FROM schema_migrations LIMIT 1`
+	var (
+		version int64
+		dirty   bool
+	)
+	err := m.DB.QueryRowContext(ctx, stmt).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSchemaVersion makes sure SchemaVersion is the newest migration.
+func TestSchemaVersion(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var newest int
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			t.Fatalf("migration %s does not start with its version", entry.Name())
+		}
+		if version > newest {
+			newest = version
+		}
+	}
+
+	if SchemaVersion != newest {
+		t.Errorf("SchemaVersion = %d, want the newest migration %d", SchemaVersion, newest)
+	}
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NotificationTarget is an incoming webhook (Slack or Mattermost) that gets a
+// message whenever one of its actions happens in the project.
+type NotificationTarget struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"projectId"`
+	URL       string    `json:"url"`
+	Actions   []string  `json:"actions"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+}
+
+// Matches reports whether the target wants to hear about the given action.
+func (t *NotificationTarget) Matches(action string) bool {
+	for _, a := range t.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+type NotificationTargetModel struct {
+	DB *sql.DB
+}
+
+func (m *NotificationTargetModel) Insert(ctx context.Context, projectID string, url string, actions []string, createdBy string) (string, error) {
+	var id string
+	for {
+		id = NewID(projectID)
+		if !m.IDExists(ctx, id) {
+			break
+		}
+	}
+
+	stmt := `INSERT INTO notification_targets (id, project_id, url, actions, created_by) VALUES (?, ?, ?, ?, ?)`
+	_, err := m.DB.ExecContext(ctx, stmt, id, projectID, url, strings.Join(actions, ","), createdBy)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *NotificationTargetModel) IDExists(ctx context.Context, id string) bool {
+	stmt := `SELECT COUNT(id) FROM notification_targets WHERE id = ?`
+	var count int
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
+	if err != nil {
+		// Handle error. For simplicity, return true to indicate an error occurred.
+		return true
+	}
+	return count > 0
+}
+
+func (m *NotificationTargetModel) Get(ctx context.Context, id string) (*NotificationTarget, error) {
+	stmt := `SELECT id, project_id, url, actions, created_at, created_by FROM notification_targets WHERE id = ?`
+	row := m.DB.QueryRowContext(ctx, stmt, id)
+
+	t := &NotificationTarget{}
+	var actions, createdAtStr string
+
+	err := row.Scan(&t.ID, &t.ProjectID, &t.URL, &actions, &createdAtStr, &t.CreatedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Notification target with ID %s not found", id)
+		}
+		return nil, err
+	}
+
+	t.Actions = splitActions(actions)
+	t.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+	}
+
+	return t, nil
+}
+
+func (m *NotificationTargetModel) GetForProjectId(ctx context.Context, projectID string) ([]*NotificationTarget, error) {
+	stmt := `SELECT id, project_id, url, actions, created_at, created_by FROM notification_targets WHERE project_id = ? ORDER BY created_at`
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []*NotificationTarget
+	for rows.Next() {
+		t := &NotificationTarget{}
+		var actions, createdAtStr string
+
+		err = rows.Scan(&t.ID, &t.ProjectID, &t.URL, &actions, &createdAtStr, &t.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Actions = splitActions(actions)
+		t.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+		}
+
+		targets = append(targets, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+func (m *NotificationTargetModel) Delete(ctx context.Context, id string) error {
+	stmt := `DELETE FROM notification_targets WHERE id = ?`
+	_, err := m.DB.ExecContext(ctx, stmt, id)
+	return err
+}
+
+func splitActions(actions string) []string {
+	if actions == "" {
+		return []string{}
+	}
+	return strings.Split(actions, ",")
+}
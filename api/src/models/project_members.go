@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Roles of a project member, from least to most privileged. RoleNone is only
+// used for the anonymous access of a project.
+const (
+	RoleNone   = "none"
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// RoleRank orders the roles, so they can be compared. Unknown roles rank lowest.
+func RoleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+type ProjectMember struct {
+	ProjectID string    `json:"projectId"`
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+}
+
+type ProjectMemberModel struct {
+	DB *sql.DB
+}
+
+// GetRole returns the role of the subject in the project, or an empty string
+// if the subject is no member.
+func (m *ProjectMemberModel) GetRole(ctx context.Context, projectID string, subject string) (string, error) {
+	stmt := `SELECT role FROM project_members WHERE project_id = ? AND subject = ?`
+	var role string
+	err := m.DB.QueryRowContext(ctx, stmt, projectID, subject).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetOwner returns the subject of the project's owner, or an empty string for
+// projects nobody owns yet.
+func (m *ProjectMemberModel) GetOwner(ctx context.Context, projectID string) (string, error) {
+	stmt := `SELECT subject FROM project_members WHERE project_id = ? AND role = ?`
+	var subject string
+	err := m.DB.QueryRowContext(ctx, stmt, projectID, RoleOwner).Scan(&subject)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return subject, err
+}
+
+func (m *ProjectMemberModel) GetForProjectId(ctx context.Context, projectID string) ([]*ProjectMember, error) {
+	stmt := `SELECT project_id, subject, role, created_at, created_by FROM project_members WHERE project_id = ? ORDER BY created_at`
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*ProjectMember
+	for rows.Next() {
+		var createdAtStr string
+		pm := &ProjectMember{}
+
+		err = rows.Scan(&pm.ProjectID, &pm.Subject, &pm.Role, &createdAtStr, &pm.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		pm.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+		}
+
+		members = append(members, pm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// Upsert adds the subject to the project or changes its role.
+func (m *ProjectMemberModel) Upsert(ctx context.Context, projectID string, subject string, role string, createdBy string) error {
+	stmt := `INSERT INTO project_members (project_id, subject, role, created_by) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`
+	_, err := m.DB.ExecContext(ctx, stmt, projectID, subject, role, createdBy)
+	return err
+}
+
+func (m *ProjectMemberModel) Delete(ctx context.Context, projectID string, subject string) (int64, error) {
+	stmt := `DELETE FROM project_members WHERE project_id = ? AND subject = ?`
+	result, err := m.DB.ExecContext(ctx, stmt, projectID, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// TransferOwnership makes the subject the new owner. The previous owner, if
+// any, stays on as an editor.
+func (m *ProjectMemberModel) TransferOwnership(ctx context.Context, projectID string, subject string, createdBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	demoteStmt := `UPDATE project_members SET role = ? WHERE project_id = ? AND role = ?`
+	if _, err := tx.ExecContext(ctx, demoteStmt, RoleEditor, projectID, RoleOwner); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	promoteStmt := `INSERT INTO project_members (project_id, subject, role, created_by) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`
+	if _, err := tx.ExecContext(ctx, promoteStmt, projectID, subject, RoleOwner, createdBy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetProjectIdsForSubject returns the IDs of all projects the subject is a member of.
+func (m *ProjectMemberModel) GetProjectIdsForSubject(ctx context.Context, subject string) ([]string, error) {
+	stmt := `SELECT project_id FROM project_members WHERE subject = ?`
+	rows, err := m.DB.QueryContext(ctx, stmt, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
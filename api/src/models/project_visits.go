@@ -0,0 +1,19 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ProjectVisitModel provides the methods to interact with the `project_visits` table.
+type ProjectVisitModel struct {
+	DB *sql.DB
+}
+
+// Record stores that the subject opened the project just now.
+func (m *ProjectVisitModel) Record(ctx context.Context, projectID string, subject string) error {
+	stmt := `INSERT INTO project_visits (project_id, subject) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE visited_at = CURRENT_TIMESTAMP`
+	_, err := m.DB.ExecContext(ctx, stmt, projectID, subject)
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -8,15 +9,16 @@ import (
 )
 
 type Project struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	StartedAt time.Time  `json:"startedAt"`
-	EndingAt  *time.Time `json:"endingAt"`
-	CreatedAt time.Time  `json:"createdAt"`
-	UpdatedAt time.Time  `json:"updatedAt"`
-	Appetite  int        `json:"appetite"`
-	Archived  bool       `json:"archived"`
-	UpdatedBy string     `json:"updatedBy"`
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndingAt        *time.Time `json:"endingAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	Appetite        int        `json:"appetite"`
+	Archived        bool       `json:"archived"`
+	UpdatedBy       string     `json:"updatedBy"`
+	AnonymousAccess string     `json:"anonymousAccess"` // role of everybody with the link who is no member.
 	// OwnerEmail    string    `json:"ownerEmail"`    // never read. Only ingested.
 	// OwnerFirstName string   `json:"ownerFirstName"` // never read. Only ingested.
 	// OwnerLastName string    `json:"ownerLastName"`  // never read. Only ingested.
@@ -26,18 +28,20 @@ type ProjectModel struct {
 	DB *sql.DB
 }
 
-func (m *ProjectModel) Insert(name string, appetite int, ownerEmail, ownerFirstName, ownerLastName, updatedBy string) (string, error) {
+// Insert creates a project. anonymousAccess is the role of everybody with the
+// link who is not a member.
+func (m *ProjectModel) Insert(ctx context.Context, name string, appetite int, ownerEmail, ownerFirstName, ownerLastName, updatedBy, anonymousAccess string) (string, error) {
 	var id string
 	for {
 		id = NewID()
-		if !m.IDExists(id) {
+		if !m.IDExists(ctx, id) {
 			break
 		}
 	}
 
 	startedAt := time.Now()
-	stmt := `INSERT INTO projects (id, name, started_at, appetite, owner_email, owner_firstname, owner_lastname, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := m.DB.Exec(stmt, id, name, startedAt, appetite, ownerEmail, ownerFirstName, ownerLastName, updatedBy)
+	stmt := `INSERT INTO projects (id, name, started_at, appetite, owner_email, owner_firstname, owner_lastname, updated_by, anonymous_access) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := m.DB.ExecContext(ctx, stmt, id, name, startedAt, appetite, ownerEmail, ownerFirstName, ownerLastName, updatedBy, anonymousAccess)
 	if err != nil {
 		return "", err
 	}
@@ -45,10 +49,10 @@ func (m *ProjectModel) Insert(name string, appetite int, ownerEmail, ownerFirstN
 	return id, nil
 }
 
-func (m *ProjectModel) IDExists(id string) bool {
+func (m *ProjectModel) IDExists(ctx context.Context, id string) bool {
 	stmt := `SELECT COUNT(id) FROM projects WHERE id = ?`
 	var count int
-	err := m.DB.QueryRow(stmt, id).Scan(&count)
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
 	if err != nil {
 		// Handle error. For simplicity, return true to indicate an error occurred.
 		return true
@@ -56,21 +60,85 @@ func (m *ProjectModel) IDExists(id string) bool {
 	return count > 0
 }
 
-func (m *ProjectModel) Get(id string) (*Project, error) {
-	stmt := `SELECT id, name, started_at, created_at, ending_at, updated_at, appetite, archived, updated_by FROM projects WHERE id = ?`
-	row := m.DB.QueryRow(stmt, id)
+const projectColumns = `id, name, started_at, created_at, ending_at, updated_at, appetite, archived, updated_by, anonymous_access`
 
+func (m *ProjectModel) Get(ctx context.Context, id string) (*Project, error) {
+	stmt := `SELECT ` + projectColumns + ` FROM projects WHERE id = ?`
+	row := m.DB.QueryRowContext(ctx, stmt, id)
+
+	p, err := scanProject(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Project with ID %s not found", id)
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetAppetitePending returns all running projects that have notification
+// targets but were not yet notified about their appetite running low.
+func (m *ProjectModel) GetAppetitePending(ctx context.Context) ([]*Project, error) {
+	stmt := `SELECT ` + projectColumns + ` FROM projects p
+		WHERE p.archived = false
+		AND p.appetite_notified = false
+		AND (p.appetite > 0 OR p.ending_at IS NOT NULL)
+		AND EXISTS (SELECT 1 FROM notification_targets n WHERE n.project_id = p.id)`
+
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetGithubSecret returns the secret GitHub signs the project's webhooks with.
+// It is empty as long as the integration was never set up. The secret is not
+// encrypted, so anybody reading the projects table can sign webhooks.
+func (m *ProjectModel) GetGithubSecret(ctx context.Context, id string) (string, error) {
+	stmt := `SELECT github_secret FROM projects WHERE id = ?`
+	var secret string
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&secret)
+	return secret, err
+}
+
+// GetOwnerEmail returns the email address the project was created with.
+func (m *ProjectModel) GetOwnerEmail(ctx context.Context, id string) (string, error) {
+	stmt := `SELECT owner_email FROM projects WHERE id = ?`
+	var email string
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&email)
+	return email, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProject(row scanner) (*Project, error) {
 	var (
 		startedAtStr, createdAtStr, updatedAtStr string
 		endingAt                                 sql.NullString // Use sql.NullString for nullable endingAt field
 		p                                        Project
 	)
 
-	err := row.Scan(&p.ID, &p.Name, &startedAtStr, &createdAtStr, &endingAt, &updatedAtStr, &p.Appetite, &p.Archived, &p.UpdatedBy)
+	err := row.Scan(&p.ID, &p.Name, &startedAtStr, &createdAtStr, &endingAt, &updatedAtStr, &p.Appetite, &p.Archived, &p.UpdatedBy, &p.AnonymousAccess)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("Project with ID %s not found", id)
-		}
 		return nil, err
 	}
 
@@ -105,7 +173,7 @@ func (m *ProjectModel) Get(id string) (*Project, error) {
 	return &p, nil
 }
 
-func (m *ProjectModel) Update(projectId string, updates map[string]interface{}) error {
+func (m *ProjectModel) Update(ctx context.Context, projectId string, updates map[string]interface{}) error {
 	queryParts := []string{}
 	args := []interface{}{}
 
@@ -117,7 +185,7 @@ func (m *ProjectModel) Update(projectId string, updates map[string]interface{})
 	sql := fmt.Sprintf("UPDATE projects SET %s WHERE id = ?", strings.Join(queryParts, ", "))
 	args = append(args, projectId)
 
-	_, err := m.DB.Exec(sql, args...)
+	_, err := m.DB.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// SearchHit is a task or bucket matching a search, with the bucket it belongs
+// to. For bucket hits, the bucket is the hit itself.
+type SearchHit struct {
+	Type    string           `json:"type"` // "task" or "bucket"
+	ID      string           `json:"id"`
+	Title   string           `json:"title"`
+	Score   float64          `json:"score"`
+	Bucket  SearchHitBucket  `json:"bucket"`
+	Project SearchHitProject `json:"project"`
+}
+
+type SearchHitBucket struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Dump    bool   `json:"dump"`
+	Done    bool   `json:"done"`
+	Flagged bool   `json:"flagged"`
+}
+
+type SearchHitProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// Search looks up tasks by title and notes and buckets by name and definition
+// of done within the given projects, using the FULLTEXT indexes. The hits are
+// ranked by relevance, best first.
+func (m *SearchModel) Search(ctx context.Context, query string, projectIDs []string, limit int) ([]*SearchHit, error) {
+	if len(projectIDs) == 0 {
+		return nil, nil
+	}
+
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(projectIDs)), ", ")
+
+	stmt := `SELECT * FROM (
+			SELECT 'task' AS type, t.id, t.title,
+				MATCH (t.title, t.notes) AGAINST (? IN NATURAL LANGUAGE MODE) AS score,
+				b.id AS bucket_id, b.name AS bucket_name, b.dump, b.done, b.flagged, p.id AS project_id, p.name AS project_name
+			FROM tasks AS t
+			JOIN buckets AS b ON b.id = t.bucket_id
+			JOIN projects AS p ON p.id = b.project_id
+			WHERE b.project_id IN (` + in + `)
+			AND MATCH (t.title, t.notes) AGAINST (? IN NATURAL LANGUAGE MODE)
+		UNION ALL
+			SELECT 'bucket' AS type, b.id, b.name,
+				MATCH (b.name, b.definition_of_done) AGAINST (? IN NATURAL LANGUAGE MODE) AS score,
+				b.id, b.name, b.dump, b.done, b.flagged, p.id, p.name
+			FROM buckets AS b
+			JOIN projects AS p ON p.id = b.project_id
+			WHERE b.project_id IN (` + in + `)
+			AND MATCH (b.name, b.definition_of_done) AGAINST (? IN NATURAL LANGUAGE MODE)
+		) AS hits
+		ORDER BY score DESC
+		LIMIT ?`
+
+	args := []interface{}{query}
+	for _, id := range projectIDs {
+		args = append(args, id)
+	}
+	args = append(args, query, query)
+	for _, id := range projectIDs {
+		args = append(args, id)
+	}
+	args = append(args, query, limit)
+
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*SearchHit
+	for rows.Next() {
+		h := &SearchHit{}
+
+		err = rows.Scan(&h.Type, &h.ID, &h.Title, &h.Score,
+			&h.Bucket.ID, &h.Bucket.Name, &h.Bucket.Dump, &h.Bucket.Done, &h.Bucket.Flagged,
+			&h.Project.ID, &h.Project.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		hits = append(hits, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ShareToken grants read-only access to a project. Only the hash of the token
+// is stored.
+type ShareToken struct {
+	ID        string     `json:"id"`
+	ProjectID string     `json:"projectId"`
+	Label     string     `json:"label"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	CreatedBy string     `json:"createdBy"`
+}
+
+// Expired reports whether the token is past its expiry date.
+func (s *ShareToken) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+type ShareTokenModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new share token and returns its ID and the token in clear
+// text, which can not be retrieved later.
+func (m *ShareTokenModel) Insert(ctx context.Context, projectID string, label string, expiresAt *time.Time, createdBy string) (string, string, error) {
+	var id string
+	for {
+		id = NewID(projectID)
+		if !m.IDExists(ctx, id) {
+			break
+		}
+	}
+
+	token, err := NewSecret(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	stmt := `INSERT INTO share_tokens (id, project_id, token_hash, label, expires_at, created_by) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = m.DB.ExecContext(ctx, stmt, id, projectID, ToSHA256Hash(token), label, expiresAt, createdBy)
+	if err != nil {
+		return "", "", err
+	}
+
+	return id, token, nil
+}
+
+func (m *ShareTokenModel) IDExists(ctx context.Context, id string) bool {
+	stmt := `SELECT COUNT(id) FROM share_tokens WHERE id = ?`
+	var count int
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
+	if err != nil {
+		// Handle error. For simplicity, return true to indicate an error occurred.
+		return true
+	}
+	return count > 0
+}
+
+func (m *ShareTokenModel) Get(ctx context.Context, id string) (*ShareToken, error) {
+	stmt := `SELECT id, project_id, label, expires_at, created_at, created_by FROM share_tokens WHERE id = ?`
+	s, err := scanShareToken(m.DB.QueryRowContext(ctx, stmt, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("Share token with ID %s not found", id)
+	}
+	return s, err
+}
+
+// GetByToken looks up a share token by its clear text. It returns nil if the
+// token does not exist, e.g. because it was revoked.
+func (m *ShareTokenModel) GetByToken(ctx context.Context, token string) (*ShareToken, error) {
+	stmt := `SELECT id, project_id, label, expires_at, created_at, created_by FROM share_tokens WHERE token_hash = ?`
+	s, err := scanShareToken(m.DB.QueryRowContext(ctx, stmt, ToSHA256Hash(token)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (m *ShareTokenModel) GetForProjectId(ctx context.Context, projectID string) ([]*ShareToken, error) {
+	stmt := `SELECT id, project_id, label, expires_at, created_at, created_by FROM share_tokens WHERE project_id = ? ORDER BY created_at`
+	rows, err := m.DB.QueryContext(ctx, stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*ShareToken
+	for rows.Next() {
+		s, err := scanShareToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (m *ShareTokenModel) Delete(ctx context.Context, id string) error {
+	stmt := `DELETE FROM share_tokens WHERE id = ?`
+	_, err := m.DB.ExecContext(ctx, stmt, id)
+	return err
+}
+
+func scanShareToken(row scanner) (*ShareToken, error) {
+	s := &ShareToken{}
+	var createdAtStr string
+	var expiresAt sql.NullString
+
+	err := row.Scan(&s.ID, &s.ProjectID, &s.Label, &expiresAt, &createdAtStr, &s.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+	}
+
+	if expiresAt.Valid {
+		expiresAtTime, err := time.Parse(DateTimeLayout, expiresAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expiresAt: %v", err)
+		}
+		s.ExpiresAt = &expiresAtTime
+	}
+
+	return s, nil
+}
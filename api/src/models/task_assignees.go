@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AssignedTask is an open task together with the project and bucket it lives in.
+type AssignedTask struct {
+	ProjectID   string
+	ProjectName string
+	BucketID    string
+	BucketName  string
+	BucketDump  bool
+	Task        *Task
+}
+
+type TaskAssigneeModel struct {
+	DB *sql.DB
+}
+
+// Replace sets the assignees of the task, removing everybody not in the list.
+func (m *TaskAssigneeModel) Replace(ctx context.Context, taskID string, usernames []string, createdBy string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	delStmt := `DELETE FROM task_assignees WHERE task_id = ?`
+	if _, err := tx.ExecContext(ctx, delStmt, taskID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	insertStmt := `INSERT INTO task_assignees (task_id, username, created_by) VALUES (?, ?, ?)`
+	for _, username := range usernames {
+		if _, err := tx.ExecContext(ctx, insertStmt, taskID, username, createdBy); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetForProjectId returns the assignees of all tasks of the project, keyed by task ID.
+func (m *TaskAssigneeModel) GetForProjectId(ctx context.Context, projectId string) (map[string][]string, error) {
+	stmt := `SELECT a.task_id, a.username
+		FROM task_assignees AS a
+		JOIN tasks AS t ON t.id = a.task_id
+		JOIN buckets AS b ON b.id = t.bucket_id
+		WHERE b.project_id = ?
+		ORDER BY a.created_at`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignees := make(map[string][]string)
+	for rows.Next() {
+		var taskID, username string
+
+		err = rows.Scan(&taskID, &username)
+		if err != nil {
+			return nil, err
+		}
+
+		assignees[taskID] = append(assignees[taskID], username)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assignees, nil
+}
+
+// GetOpenForUsername returns the open tasks assigned to the user in all
+// projects that are not archived, ordered by project, bucket and task priority.
+func (m *TaskAssigneeModel) GetOpenForUsername(ctx context.Context, username string) ([]*AssignedTask, error) {
+	stmt := `SELECT p.id, p.name, b.id, b.name, b.dump,
+			t.id, t.title, COALESCE(t.notes, ''), t.closed, t.bucket_id, t.priority, t.created_at, t.updated_at, t.updated_by
+		FROM task_assignees AS a
+		JOIN tasks AS t ON t.id = a.task_id
+		JOIN buckets AS b ON b.id = t.bucket_id
+		JOIN projects AS p ON p.id = b.project_id
+		WHERE a.username = ? AND t.closed = false AND p.archived = false
+		ORDER BY p.updated_at DESC, p.id, b.priority, t.priority`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assigned []*AssignedTask
+	for rows.Next() {
+		var createdAtStr, updatedAtStr string
+		a := &AssignedTask{Task: &Task{}}
+		t := a.Task
+
+		err = rows.Scan(&a.ProjectID, &a.ProjectName, &a.BucketID, &a.BucketName, &a.BucketDump,
+			&t.ID, &t.Title, &t.Notes, &t.Closed, &t.BucketID, &t.Priority, &createdAtStr, &updatedAtStr, &t.UpdatedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		t.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+		}
+
+		t.UpdatedAt, err = time.Parse(DateTimeLayout, updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updatedAt: %v", err)
+		}
+
+		assigned = append(assigned, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assigned, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TaskLink points from a task to something outside of dump.link, e.g. the pull
+// request that implemented it.
+type TaskLink struct {
+	TaskID    string    `json:"taskId"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+}
+
+type TaskLinkModel struct {
+	DB *sql.DB
+}
+
+func (m *TaskLinkModel) Insert(ctx context.Context, taskID string, url string, title string, createdBy string) error {
+	stmt := `INSERT INTO task_links (task_id, url, title, created_by) VALUES (?, ?, ?, ?)`
+	_, err := m.DB.ExecContext(ctx, stmt, taskID, url, title, createdBy)
+	return err
+}
+
+func (m *TaskLinkModel) Exists(ctx context.Context, taskID string, url string) (bool, error) {
+	var exists bool
+	stmt := `SELECT EXISTS(SELECT 1 FROM task_links WHERE task_id = ? AND url = ?)`
+	err := m.DB.QueryRowContext(ctx, stmt, taskID, url).Scan(&exists)
+	return exists, err
+}
+
+func (m *TaskLinkModel) GetForProjectId(ctx context.Context, projectId string) ([]*TaskLink, error) {
+	stmt := `SELECT l.task_id, l.url, l.title, l.created_at, l.created_by
+		FROM task_links AS l
+		JOIN tasks AS t ON t.id = l.task_id
+		JOIN buckets AS b ON b.id = t.bucket_id
+		WHERE b.project_id = ?
+		ORDER BY l.created_at`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*TaskLink
+	for rows.Next() {
+		var createdAtStr string
+		l := &TaskLink{}
+
+		err = rows.Scan(&l.TaskID, &l.URL, &l.Title, &createdAtStr, &l.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		l.CreatedAt, err = time.Parse(DateTimeLayout, createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt: %v", err)
+		}
+
+		links = append(links, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
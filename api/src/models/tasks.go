@@ -1,8 +1,10 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 )
@@ -10,21 +12,23 @@ import (
 type Task struct {
 	ID        string    `json:"id"`
 	Title     string    `json:"title"`
+	Notes     string    `json:"notes"` // Markdown
 	Closed    bool      `json:"closed"`
 	BucketID  string    `json:"bucketId"`
 	Priority  int       `json:"priority"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	UpdatedBy string    `json:"updatedBy"`
+	Assignees []string  `json:"assignees"`
 }
 
 type TaskModel struct {
 	DB *sql.DB
 }
 
-func (m *TaskModel) Insert(id string, title string, closed bool, bucketID string, priority int, projectId string, updatedBy string) (string, error) {
+func (m *TaskModel) Insert(ctx context.Context, id string, title string, closed bool, bucketID string, priority int, projectId string, updatedBy string) (string, error) {
 	stmt := `INSERT INTO tasks (id, title, closed, bucket_id, priority, updated_by) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := m.DB.Exec(stmt, id, title, closed, bucketID, priority, updatedBy)
+	_, err := m.DB.ExecContext(ctx, stmt, id, title, closed, bucketID, priority, updatedBy)
 	if err != nil {
 		return "", err
 	}
@@ -32,26 +36,26 @@ func (m *TaskModel) Insert(id string, title string, closed bool, bucketID string
 	return id, nil
 }
 
-func (m *TaskModel) IDExists(id string) bool {
-	fmt.Println("IDExists", id)
+func (m *TaskModel) IDExists(ctx context.Context, id string) bool {
 	stmt := `SELECT COUNT(id) FROM tasks WHERE id = ?`
 	var count int
-	err := m.DB.QueryRow(stmt, id).Scan(&count)
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&count)
 	if err != nil {
 		// Handle error. For simplicity, return true to indicate an error occurred.
+		slog.ErrorContext(ctx, "checking task id", "taskId", id, "error", err)
 		return true
 	}
 	return count > 0
 }
 
-func (m *TaskModel) Get(id string) (*Task, error) {
-	stmt := `SELECT id, title, closed, bucket_id, priority, created_at, updated_at, updated_by FROM tasks WHERE id = ?`
-	row := m.DB.QueryRow(stmt, id)
+func (m *TaskModel) Get(ctx context.Context, id string) (*Task, error) {
+	stmt := `SELECT id, title, COALESCE(notes, ''), closed, bucket_id, priority, created_at, updated_at, updated_by FROM tasks WHERE id = ?`
+	row := m.DB.QueryRowContext(ctx, stmt, id)
 
 	t := &Task{}
 	var createdAtStr, updatedAtStr string
 
-	err := row.Scan(&t.ID, &t.Title, &t.Closed, &t.BucketID, &t.Priority, &createdAtStr, &updatedAtStr, &t.UpdatedBy)
+	err := row.Scan(&t.ID, &t.Title, &t.Notes, &t.Closed, &t.BucketID, &t.Priority, &createdAtStr, &updatedAtStr, &t.UpdatedBy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("Task with ID %s not found", id)
@@ -72,15 +76,15 @@ func (m *TaskModel) Get(id string) (*Task, error) {
 	return t, nil
 }
 
-func (m *TaskModel) GetForProjectId(projectId string) ([]*Task, error) {
-	stmt := `SELECT t.id, t.title, t.closed, t.bucket_id, t.priority, t.created_at, t.updated_at, t.updated_by
+func (m *TaskModel) GetForProjectId(ctx context.Context, projectId string) ([]*Task, error) {
+	stmt := `SELECT t.id, t.title, COALESCE(t.notes, ''), t.closed, t.bucket_id, t.priority, t.created_at, t.updated_at, t.updated_by
 		FROM tasks AS t
 		WHERE t.bucket_id IN (
 			SELECT b.id
 			FROM buckets AS b
 			WHERE b.project_id = ?)`
 
-	rows, err := m.DB.Query(stmt, projectId)
+	rows, err := m.DB.QueryContext(ctx, stmt, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +95,7 @@ func (m *TaskModel) GetForProjectId(projectId string) ([]*Task, error) {
 		var createdAtStr, updatedAtStr string
 		t := &Task{}
 
-		err := rows.Scan(&t.ID, &t.Title, &t.Closed, &t.BucketID, &t.Priority, &createdAtStr, &updatedAtStr, &t.UpdatedBy)
+		err := rows.Scan(&t.ID, &t.Title, &t.Notes, &t.Closed, &t.BucketID, &t.Priority, &createdAtStr, &updatedAtStr, &t.UpdatedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -116,10 +120,10 @@ func (m *TaskModel) GetForProjectId(projectId string) ([]*Task, error) {
 	return tasks, nil
 }
 
-func (m *TaskModel) Delete(taskId string) error {
+func (m *TaskModel) Delete(ctx context.Context, taskId string) error {
 	stmt := `DELETE FROM tasks WHERE id = ?`
 
-	_, err := m.DB.Exec(stmt, taskId)
+	_, err := m.DB.ExecContext(ctx, stmt, taskId)
 	if err != nil {
 		return err
 	}
@@ -127,7 +131,7 @@ func (m *TaskModel) Delete(taskId string) error {
 	return nil
 }
 
-func (m *TaskModel) Update(taskId string, updates map[string]interface{}) error {
+func (m *TaskModel) Update(ctx context.Context, taskId string, updates map[string]interface{}) error {
 	// Build the SQL query dynamically based on the updates map
 	queryParts := []string{}
 	args := []interface{}{}
@@ -140,7 +144,7 @@ func (m *TaskModel) Update(taskId string, updates map[string]interface{}) error
 	sql := fmt.Sprintf("UPDATE tasks SET %s WHERE id = ?", strings.Join(queryParts, ", "))
 	args = append(args, taskId)
 
-	_, err := m.DB.Exec(sql, args...)
+	_, err := m.DB.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,378 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"dump.link/src/models"
+)
+
+// notifiableActions are the actions a notification target can subscribe to.
+var notifiableActions = map[ActionType]bool{
+	ActionAddTask:                true,
+	ActionUpdateTask:             true,
+	ActionDeleteTask:             true,
+	ActionUpdateBucket:           true,
+	ActionResetBucketLayers:      true,
+	ActionAddBucketDependency:    true,
+	ActionRemoveBucketDependency: true,
+	ActionUpdateProject:          true,
+	ActionResetProjectLayers:     true,
+	ActionFlagBucket:             true,
+	ActionDoneBucket:             true,
+	ActionAppetiteWarning:        true,
+}
+
+// appetiteWarningThreshold is the share of the appetite after which
+// ActionAppetiteWarning is sent.
+const appetiteWarningThreshold = 0.75
+
+// notificationMessage is the payload of Slack and Mattermost incoming webhooks.
+type notificationMessage struct {
+	Text string `json:"text"`
+}
+
+// notificationSubject holds the names a message is rendered with.
+type notificationSubject struct {
+	ProjectURL  string
+	ProjectName string
+	BucketName  string
+	OtherBucket string
+	TaskTitle   string
+	Username    string
+}
+
+// notify sends the action to all matching notification targets of the
+// project. It runs in the background, a slow webhook must never delay the
+// response to the client.
+func (app *application) notify(ctx context.Context, projectId string, action ActionType, data any, username string) {
+	if !app.config.Features.Notifications {
+		return
+	}
+
+	// the request may be over by the time the webhooks are posted.
+	ctx = context.WithoutCancel(ctx)
+
+	app.background(func() {
+		err := app.sendNotifications(ctx, projectId, action, data, username)
+		if err != nil {
+			app.logger.ErrorContext(ctx, "sending notifications failed", "error", err, "projectId", projectId, "action", action)
+		}
+	})
+}
+
+func (app *application) sendNotifications(ctx context.Context, projectId string, action ActionType, data any, username string) error {
+	targets, err := app.notificationTargets.GetForProjectId(ctx, projectId)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	actions := notificationActions(action, data)
+
+	var subject *notificationSubject
+	for _, target := range targets {
+		matched, ok := matchNotificationTarget(target, actions)
+		if !ok {
+			continue
+		}
+
+		// resolve the names only once, and only if anybody listens.
+		if subject == nil {
+			subject, err = app.resolveNotificationSubject(ctx, projectId, action, data, username)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = app.postNotification(target.URL, notificationText(matched, subject))
+		if err != nil {
+			app.logger.ErrorContext(ctx, "notification failed", "error", err, "projectId", projectId, "targetId", target.ID, "action", matched)
+		}
+	}
+
+	return nil
+}
+
+// notificationActions returns the action itself plus the more specific
+// actions that can be derived from its data, most specific last.
+func notificationActions(action ActionType, data any) []ActionType {
+	actions := []ActionType{action}
+
+	if action == ActionUpdateBucket {
+		if flagged, ok := envelopeValue(data, "flagged").(bool); ok && flagged {
+			actions = append(actions, ActionFlagBucket)
+		}
+		if done, ok := envelopeValue(data, "done").(bool); ok && done {
+			actions = append(actions, ActionDoneBucket)
+		}
+	}
+
+	return actions
+}
+
+// matchNotificationTarget returns the most specific action the target listens to.
+func matchNotificationTarget(target *models.NotificationTarget, actions []ActionType) (ActionType, bool) {
+	for i := len(actions) - 1; i >= 0; i-- {
+		if target.Matches(string(actions[i])) {
+			return actions[i], true
+		}
+	}
+	return "", false
+}
+
+func (app *application) resolveNotificationSubject(ctx context.Context, projectId string, action ActionType, data any, username string) (*notificationSubject, error) {
+	project, err := app.projects.Get(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := &notificationSubject{
+		ProjectURL:  fmt.Sprintf("%s/a/%s", app.config.AppURL, project.ID),
+		ProjectName: project.Name,
+		Username:    username,
+	}
+
+	bucketId := ""
+	switch action {
+	case ActionAddTask:
+		if task, ok := data.(*models.Task); ok {
+			subject.TaskTitle = task.Title
+			bucketId = task.BucketID
+		}
+	case ActionDeleteTask:
+		// the task is gone, its handler passes what is left to tell.
+		subject.TaskTitle = envelopeString(data, "title")
+		bucketId = envelopeString(data, "bucketId")
+	case ActionUpdateTask:
+		task, err := app.tasks.Get(ctx, envelopeString(data, "id"))
+		if err != nil {
+			return nil, err
+		}
+		subject.TaskTitle = task.Title
+		bucketId = task.BucketID
+	case ActionUpdateBucket, ActionResetBucketLayers:
+		bucketId = envelopeString(data, "id")
+	case ActionAddBucketDependency, ActionRemoveBucketDependency:
+		bucketId = envelopeString(data, "bucketId")
+		other, err := app.buckets.Get(ctx, envelopeString(data, "dependencyId"))
+		if err != nil {
+			return nil, err
+		}
+		subject.OtherBucket = bucketName(other)
+	}
+
+	if bucketId != "" {
+		bucket, err := app.buckets.Get(ctx, bucketId)
+		if err != nil {
+			return nil, err
+		}
+		subject.BucketName = bucketName(bucket)
+	}
+
+	return subject, nil
+}
+
+// slackEscaper escapes the control characters of Slack markup, so that
+// names can not mention @channel or break out of a link.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// notificationText renders the message in Slack markup, which Mattermost understands as well.
+func notificationText(action ActionType, subject *notificationSubject) string {
+	// everything but the URL is entered by the users.
+	s := *subject
+	for _, field := range []*string{&s.ProjectName, &s.Username, &s.TaskTitle, &s.BucketName, &s.OtherBucket} {
+		*field = slackEscaper.Replace(*field)
+	}
+
+	who := s.Username
+	if who == "" {
+		who = "Somebody"
+	}
+	project := fmt.Sprintf("<%s|%s>", s.ProjectURL, s.ProjectName)
+
+	switch action {
+	case ActionAddTask:
+		return fmt.Sprintf("%s added task *%s* to scope *%s* in %s", who, s.TaskTitle, s.BucketName, project)
+	case ActionUpdateTask:
+		return fmt.Sprintf("%s updated task *%s* in scope *%s* in %s", who, s.TaskTitle, s.BucketName, project)
+	case ActionDeleteTask:
+		return fmt.Sprintf("%s deleted task *%s* from scope *%s* in %s", who, s.TaskTitle, s.BucketName, project)
+	case ActionUpdateBucket:
+		return fmt.Sprintf("%s updated scope *%s* in %s", who, s.BucketName, project)
+	case ActionFlagBucket:
+		return fmt.Sprintf(":triangular_flag_on_post: %s flagged scope *%s* in %s", who, s.BucketName, project)
+	case ActionDoneBucket:
+		return fmt.Sprintf(":white_check_mark: %s marked scope *%s* as done in %s", who, s.BucketName, project)
+	case ActionResetBucketLayers:
+		return fmt.Sprintf("%s reset the sequence of scope *%s* in %s", who, s.BucketName, project)
+	case ActionAddBucketDependency:
+		return fmt.Sprintf("%s made scope *%s* depend on *%s* in %s", who, s.BucketName, s.OtherBucket, project)
+	case ActionRemoveBucketDependency:
+		return fmt.Sprintf("%s removed the dependency of scope *%s* on *%s* in %s", who, s.BucketName, s.OtherBucket, project)
+	case ActionUpdateProject:
+		return fmt.Sprintf("%s updated the settings of %s", who, project)
+	case ActionResetProjectLayers:
+		return fmt.Sprintf("%s reset the sequence of all scopes in %s", who, project)
+	case ActionAppetiteWarning:
+		return fmt.Sprintf(":hourglass_flowing_sand: %s has used %d%% of its appetite", project, int(appetiteWarningThreshold*100))
+	default:
+		return fmt.Sprintf("%s: %s in %s", who, action, project)
+	}
+}
+
+func (app *application) postNotification(url string, text string) error {
+	body, err := json.Marshal(notificationMessage{Text: text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := app.webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// checkAppetites notifies every project that crossed the appetite threshold
+// since the last run. It is called by the scheduler.
+func (app *application) checkAppetites() {
+	ctx := context.Background()
+
+	projects, err := app.projects.GetAppetitePending(ctx)
+	if err != nil {
+		app.logger.Error("loading projects for the appetite check failed", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, project := range projects {
+		if appetiteProgress(project, now) < appetiteWarningThreshold {
+			continue
+		}
+
+		err = app.projects.Update(ctx, project.ID, map[string]interface{}{"appetite_notified": true})
+		if err != nil {
+			app.logger.Error("marking the appetite as notified failed", "error", err, "projectId", project.ID)
+			continue
+		}
+
+		app.notify(ctx, project.ID, ActionAppetiteWarning, nil, "")
+	}
+}
+
+// appetiteProgress returns the used share of the project's time box. The
+// appetite is given in weeks; without one, the end date is the limit.
+func appetiteProgress(project *models.Project, now time.Time) float64 {
+	end, ok := appetiteEnd(project)
+	if !ok {
+		return 0
+	}
+
+	total := end.Sub(project.StartedAt)
+	if total <= 0 {
+		return 1
+	}
+
+	return float64(now.Sub(project.StartedAt)) / float64(total)
+}
+
+// appetiteEnd returns when the time box of the project ends. The appetite in
+// weeks takes precedence over the ending date.
+func appetiteEnd(project *models.Project) (time.Time, bool) {
+	switch {
+	case project.Appetite > 0:
+		return project.StartedAt.AddDate(0, 0, project.Appetite*7), true
+	case project.EndingAt != nil:
+		return *project.EndingAt, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func bucketName(bucket *models.Bucket) string {
+	if bucket.Dump {
+		return "Dump"
+	}
+	if bucket.Name == "" {
+		return "Unnamed"
+	}
+	return bucket.Name
+}
+
+func envelopeValue(data any, key string) any {
+	if e, ok := data.(envelope); ok {
+		return e[key]
+	}
+	return nil
+}
+
+func envelopeString(data any, key string) string {
+	s, _ := envelopeValue(data, key).(string)
+	return s
+}
+
+// errPrivateAddress is returned for webhooks pointing into the internal network.
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newWebhookClient returns the client posting to the notification targets.
+// Any editor can add a target, so unless allowPrivate is set, e.g. for a
+// Mattermost in the same network, it refuses to connect to loopback, private
+// and link-local addresses like the metadata service of the cloud. The check
+// runs on the resolved address of every connection, redirects included.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !allowPrivate {
+		dialer.Control = publicAddressOnly
+		// a proxy would be the only address checked.
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: 5 * time.Second, Transport: transport}
+}
+
+func publicAddressOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !publicAddress(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, ip)
+	}
+	return nil
+}
+
+// publicAddress reports whether the IP is routable on the internet.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
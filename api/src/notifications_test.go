@@ -0,0 +1,204 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"dump.link/src/models"
+)
+
+func TestNotificationActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		action ActionType
+		data   any
+		want   []ActionType
+	}{
+		{"Rename", ActionUpdateBucket, envelope{"name": "Login"}, []ActionType{ActionUpdateBucket}},
+		{"Flagged", ActionUpdateBucket, envelope{"flagged": true}, []ActionType{ActionUpdateBucket, ActionFlagBucket}},
+		{"Unflagged", ActionUpdateBucket, envelope{"flagged": false}, []ActionType{ActionUpdateBucket}},
+		{"Done", ActionUpdateBucket, envelope{"done": true}, []ActionType{ActionUpdateBucket, ActionDoneBucket}},
+		{"Task", ActionUpdateTask, envelope{"closed": true}, []ActionType{ActionUpdateTask}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := notificationActions(tt.action, tt.data)
+			if len(got) != len(tt.want) {
+				t.Fatalf("notificationActions() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("notificationActions() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchNotificationTarget(t *testing.T) {
+	target := &models.NotificationTarget{Actions: []string{string(ActionUpdateBucket), string(ActionFlagBucket)}}
+
+	got, ok := matchNotificationTarget(target, []ActionType{ActionUpdateBucket, ActionFlagBucket})
+	if !ok || got != ActionFlagBucket {
+		t.Errorf("matchNotificationTarget() = %v, %v, want %v", got, ok, ActionFlagBucket)
+	}
+
+	_, ok = matchNotificationTarget(target, []ActionType{ActionAddTask})
+	if ok {
+		t.Errorf("matchNotificationTarget() matched an action the target does not listen to")
+	}
+}
+
+func TestNotificationText(t *testing.T) {
+	subject := &notificationSubject{
+		ProjectURL:  "https://dump.link/a/abcdefghijk",
+		ProjectName: "Checkout",
+		BucketName:  "Payment",
+		TaskTitle:   "Add PayPal",
+		Username:    "Ada",
+	}
+
+	got := notificationText(ActionFlagBucket, subject)
+	for _, want := range []string{"Ada", "*Payment*", "|Checkout>", "/a/abcdefghijk"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("notificationText() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestPostNotification(t *testing.T) {
+	var received notificationMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// the test servers listen on the loopback interface.
+	app := &application{webhookClient: newWebhookClient(true)}
+
+	err := app.postNotification(server.URL, "hello")
+	if err != nil {
+		t.Fatalf("postNotification() error = %v", err)
+	}
+	if received.Text != "hello" {
+		t.Errorf("received text = %q, want %q", received.Text, "hello")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	err = app.postNotification(failing.URL, "hello")
+	if err == nil {
+		t.Errorf("postNotification() expected an error for status 403")
+	}
+}
+
+func TestPostNotificationPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the webhook reached a loopback address")
+	}))
+	defer server.Close()
+
+	app := &application{webhookClient: newWebhookClient(false)}
+
+	err := app.postNotification(server.URL, "hello")
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("postNotification() error = %v, want %v", err, errPrivateAddress)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"203.0.113.7":     true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"fdaa::3":         false,
+		"0.0.0.0":         false,
+		"::ffff:10.0.0.1": false,
+	}
+
+	for addr, want := range tests {
+		if got := publicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestNotificationTextDeletedTask(t *testing.T) {
+	subject := &notificationSubject{
+		ProjectURL:  "https://dump.link/a/abcdefghijk",
+		ProjectName: "Checkout",
+		BucketName:  "Payment",
+		TaskTitle:   "Add PayPal",
+		Username:    "Ada",
+	}
+
+	got := notificationText(ActionDeleteTask, subject)
+	if want := "Ada deleted task *Add PayPal* from scope *Payment*"; !strings.Contains(got, want) {
+		t.Errorf("notificationText() = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestNotificationTextEscapesMarkup(t *testing.T) {
+	subject := &notificationSubject{
+		ProjectURL:  "https://dump.link/a/abcdefghijk",
+		ProjectName: "Checkout|https://evil.example>",
+		BucketName:  "Payment & Billing",
+		TaskTitle:   "<!channel> pay up",
+		Username:    "<@U123>",
+	}
+
+	got := notificationText(ActionAddTask, subject)
+	want := "&lt;@U123&gt; added task *&lt;!channel&gt; pay up* to scope *Payment &amp; Billing* in <https://dump.link/a/abcdefghijk|Checkout|https://evil.example&gt;>"
+	if got != want {
+		t.Errorf("notificationText() = %q, want %q", got, want)
+	}
+	if subject.TaskTitle != "<!channel> pay up" {
+		t.Errorf("notificationText() changed the subject to %+v", subject)
+	}
+}
+
+func TestAppetiteProgress(t *testing.T) {
+	startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endingAt := startedAt.AddDate(0, 0, 10)
+
+	tests := []struct {
+		name    string
+		project *models.Project
+		now     time.Time
+		want    float64
+	}{
+		{"Appetite", &models.Project{StartedAt: startedAt, Appetite: 2}, startedAt.AddDate(0, 0, 7), 0.5},
+		{"EndingAt", &models.Project{StartedAt: startedAt, EndingAt: &endingAt}, startedAt.AddDate(0, 0, 8), 0.8},
+		{"AppetiteWins", &models.Project{StartedAt: startedAt, Appetite: 1, EndingAt: &endingAt}, startedAt.AddDate(0, 0, 7), 1},
+		{"NoTimebox", &models.Project{StartedAt: startedAt}, startedAt.AddDate(0, 0, 100), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appetiteProgress(tt.project, tt.now)
+			if got != tt.want {
+				t.Errorf("appetiteProgress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package src
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/auth0/go-jwt-middleware/v2/jwks"
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	"gopkg.in/go-jose/go-jose.v2"
+)
+
+// oidcConfig describes which JWTs are accepted. It works with any OpenID
+// Connect provider, Auth0 is just the default.
+type oidcConfig struct {
+	Issuer     string
+	Audience   []string
+	Algorithms []validator.SignatureAlgorithm
+
+	// JWKSURL overrides the jwks_uri of the issuer's discovery document.
+	JWKSURL string
+	// JWKSFile is a local JSON Web Key Set. It takes precedence over JWKSURL.
+	JWKSFile string
+
+	// UsernameClaim identifies the user, e.g. in project memberships.
+	UsernameClaim string
+	// ScopeClaim holds the scopes, as a space separated string or an array.
+	ScopeClaim string
+	// EmailClaim holds the email address. It only counts if the claim of the
+	// same name with a _verified suffix is true, e.g. email_verified.
+	EmailClaim string
+}
+
+// oidcConfigFromEnv reads the OIDC_* environment variables. Without an
+// OIDC_ISSUER, the issuer and audience fall back to AUTH0_DOMAIN and
+// AUTH0_AUDIENCE.
+func oidcConfigFromEnv(getenv func(string) string) oidcConfig {
+	cfg := oidcConfig{
+		Issuer:        getenv("OIDC_ISSUER"),
+		Audience:      splitList(getenv("OIDC_AUDIENCE")),
+		JWKSURL:       getenv("OIDC_JWKS_URL"),
+		JWKSFile:      getenv("OIDC_JWKS_FILE"),
+		UsernameClaim: getenv("OIDC_USERNAME_CLAIM"),
+		ScopeClaim:    getenv("OIDC_SCOPE_CLAIM"),
+		EmailClaim:    getenv("OIDC_EMAIL_CLAIM"),
+	}
+
+	if cfg.Issuer == "" && getenv("AUTH0_DOMAIN") != "" {
+		cfg.Issuer = "https://" + getenv("AUTH0_DOMAIN") + "/"
+	}
+	if len(cfg.Audience) == 0 {
+		cfg.Audience = splitList(getenv("AUTH0_AUDIENCE"))
+	}
+
+	for _, alg := range splitList(getenv("OIDC_ALGORITHMS")) {
+		cfg.Algorithms = append(cfg.Algorithms, validator.SignatureAlgorithm(alg))
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []validator.SignatureAlgorithm{validator.RS256}
+	}
+
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "sub"
+	}
+	if cfg.ScopeClaim == "" {
+		cfg.ScopeClaim = "scope"
+	}
+	if cfg.EmailClaim == "" {
+		cfg.EmailClaim = "email"
+	}
+
+	return cfg
+}
+
+// tokenVerifier validates JWTs signed with any of the configured algorithms.
+type tokenVerifier struct {
+	config     oidcConfig
+	keyFunc    func(context.Context) (interface{}, error)
+	validators map[string]*validator.Validator
+}
+
+func newTokenVerifier(cfg oidcConfig) (*tokenVerifier, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("oidc: issuer is required")
+	}
+	if len(cfg.Audience) == 0 {
+		return nil, errors.New("oidc: audience is required")
+	}
+	if len(cfg.Algorithms) == 0 {
+		return nil, errors.New("oidc: at least one algorithm is required")
+	}
+
+	issuerURL, err := url.Parse(cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid issuer: %w", err)
+	}
+
+	var keyFunc func(context.Context) (interface{}, error)
+	switch {
+	case cfg.JWKSFile != "":
+		keySet, err := readJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFunc = func(context.Context) (interface{}, error) { return keySet, nil }
+	case cfg.JWKSURL != "":
+		jwksURL, err := url.Parse(cfg.JWKSURL)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: invalid JWKS URL: %w", err)
+		}
+		keyFunc = jwks.NewCachingProvider(issuerURL, 5*time.Minute, jwks.WithCustomJWKSURI(jwksURL)).KeyFunc
+	default:
+		keyFunc = jwks.NewCachingProvider(issuerURL, 5*time.Minute).KeyFunc
+	}
+
+	v := &tokenVerifier{
+		config:     cfg,
+		keyFunc:    keyFunc,
+		validators: make(map[string]*validator.Validator),
+	}
+
+	for _, alg := range cfg.Algorithms {
+		algValidator, err := validator.New(
+			keyFunc,
+			alg,
+			issuerURL.String(),
+			cfg.Audience,
+			validator.WithCustomClaims(func() validator.CustomClaims {
+				return &tokenClaims{}
+			}),
+			validator.WithAllowedClockSkew(time.Minute),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: %w", err)
+		}
+		v.validators[string(alg)] = algValidator
+	}
+
+	return v, nil
+}
+
+// fetchKeys loads the key set, which fills the cache of a remote JWKS.
+func (v *tokenVerifier) fetchKeys(ctx context.Context) error {
+	_, err := v.keyFunc(ctx)
+	return err
+}
+
+// ValidateToken picks the validator for the token's alg header and maps the
+// username and scopes from the configured claims.
+func (v *tokenVerifier) ValidateToken(ctx context.Context, token string) (interface{}, error) {
+	alg, err := tokenAlgorithm(token)
+	if err != nil {
+		return nil, err
+	}
+
+	algValidator, ok := v.validators[alg]
+	if !ok {
+		return nil, fmt.Errorf("signing algorithm %q is not allowed", alg)
+	}
+
+	result, err := algValidator.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	validated := result.(*validator.ValidatedClaims)
+	claims := validated.CustomClaims.(*tokenClaims)
+	claims.Username = claims.stringClaim(v.config.UsernameClaim)
+	claims.Scopes = claims.listClaim(v.config.ScopeClaim)
+	if verified, _ := claims.raw[v.config.EmailClaim+"_verified"].(bool); verified {
+		claims.Email = claims.stringClaim(v.config.EmailClaim)
+	}
+
+	if claims.Username == "" {
+		return nil, fmt.Errorf("token has no %q claim", v.config.UsernameClaim)
+	}
+
+	return validated, nil
+}
+
+// tokenClaims keeps all claims of a token so that the username and scopes can
+// be read from whichever claims the provider uses.
+type tokenClaims struct {
+	raw map[string]any
+
+	Username string
+	Scopes   []string
+	// Email is only set if the provider verified it.
+	Email string
+}
+
+func (c *tokenClaims) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &c.raw)
+}
+
+// Validate satisfies validator.CustomClaims. The registered claims are
+// checked by the validator itself.
+func (c *tokenClaims) Validate(ctx context.Context) error {
+	return nil
+}
+
+// HasScope checks whether the token was granted a specific scope.
+func (c *tokenClaims) HasScope(expectedScope string) bool {
+	for _, scope := range c.Scopes {
+		if scope == expectedScope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *tokenClaims) stringClaim(name string) string {
+	value, _ := c.raw[name].(string)
+	return value
+}
+
+func (c *tokenClaims) listClaim(name string) []string {
+	switch value := c.raw[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		list := []string{}
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+
+	return nil
+}
+
+// tokenAlgorithm reads the alg from the JOSE header without verifying the
+// token, only to pick the right validator.
+func tokenAlgorithm(token string) (string, error) {
+	header, _, found := strings.Cut(token, ".")
+	if !found {
+		return "", errors.New("malformed token")
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return "", fmt.Errorf("malformed token header: %w", err)
+	}
+
+	var input struct {
+		Alg string `json:"alg"`
+	}
+	err = json.Unmarshal(decoded, &input)
+	if err != nil {
+		return "", fmt.Errorf("malformed token header: %w", err)
+	}
+
+	return input.Alg, nil
+}
+
+func readJWKSFile(path string) (*jose.JSONWebKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: reading JWKS file: %w", err)
+	}
+
+	var keySet jose.JSONWebKeySet
+	err = json.Unmarshal(data, &keySet)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parsing JWKS file: %w", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, fmt.Errorf("oidc: JWKS file %s has no keys", path)
+	}
+
+	return &keySet, nil
+}
+
+// splitList splits a comma separated environment variable.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,180 @@
+package src
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/auth0/go-jwt-middleware/v2/validator"
+	"gopkg.in/go-jose/go-jose.v2"
+	"gopkg.in/go-jose/go-jose.v2/jwt"
+)
+
+const testIssuer = "https://issuer.test/"
+
+// newTestVerifier writes the public half of a fresh RSA key to a JWKS file
+// and returns a verifier using it, plus a function signing tokens.
+func newTestVerifier(t *testing.T, cfg oidcConfig) (*tokenVerifier, func(claims map[string]any) string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keySet := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+	}}
+	data, err := json.Marshal(keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.JWKSFile = filepath.Join(t.TempDir(), "jwks.json")
+	err = os.WriteFile(cfg.JWKSFile, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := newTokenVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(claims map[string]any) string {
+		token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	return verifier, sign
+}
+
+func TestTokenVerifier(t *testing.T) {
+	cfg := oidcConfig{
+		Issuer:        testIssuer,
+		Audience:      []string{"dump.link"},
+		Algorithms:    []validator.SignatureAlgorithm{validator.RS256, validator.ES256},
+		UsernameClaim: "email",
+		ScopeClaim:    "scp",
+		EmailClaim:    "email",
+	}
+	verifier, sign := newTestVerifier(t, cfg)
+
+	valid := map[string]any{
+		"iss":   testIssuer,
+		"aud":   "dump.link",
+		"sub":   "auth0|123",
+		"email": "ada@example.com",
+		"scp":   []string{"read:projects", "write:projects"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+
+	result, err := verifier.ValidateToken(context.Background(), sign(valid))
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	claims := result.(*validator.ValidatedClaims).CustomClaims.(*tokenClaims)
+	if claims.Username != "ada@example.com" {
+		t.Errorf("Username = %q, want %q", claims.Username, "ada@example.com")
+	}
+	if !claims.HasScope("write:projects") || claims.HasScope("admin") {
+		t.Errorf("Scopes = %v", claims.Scopes)
+	}
+	if claims.Email != "" {
+		t.Errorf("Email = %q without email_verified, want none", claims.Email)
+	}
+
+	verified := map[string]any{"email_verified": true}
+	for k, v := range valid {
+		verified[k] = v
+	}
+	result, err = verifier.ValidateToken(context.Background(), sign(verified))
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if email := result.(*validator.ValidatedClaims).CustomClaims.(*tokenClaims).Email; email != "ada@example.com" {
+		t.Errorf("Email = %q, want %q", email, "ada@example.com")
+	}
+
+	invalid := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{"Issuer", map[string]any{"iss": "https://other.test/"}},
+		{"Audience", map[string]any{"aud": "other"}},
+		{"Expired", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"No username", map[string]any{"email": nil}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{}
+			for k, v := range valid {
+				claims[k] = v
+			}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+
+			_, err := verifier.ValidateToken(context.Background(), sign(claims))
+			if err == nil {
+				t.Error("ValidateToken() accepted an invalid token")
+			}
+		})
+	}
+}
+
+func TestTokenVerifierAlgorithms(t *testing.T) {
+	cfg := oidcConfig{
+		Issuer:        testIssuer,
+		Audience:      []string{"dump.link"},
+		Algorithms:    []validator.SignatureAlgorithm{validator.ES256},
+		UsernameClaim: "sub",
+		ScopeClaim:    "scope",
+	}
+	verifier, sign := newTestVerifier(t, cfg)
+
+	token := sign(map[string]any{"iss": testIssuer, "aud": "dump.link", "sub": "auth0|123"})
+	_, err := verifier.ValidateToken(context.Background(), token)
+	if err == nil {
+		t.Error("ValidateToken() accepted a token signed with a disallowed algorithm")
+	}
+}
+
+func TestNewTokenVerifierConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  oidcConfig
+	}{
+		{"No issuer", oidcConfig{Audience: []string{"dump.link"}, Algorithms: []validator.SignatureAlgorithm{validator.RS256}}},
+		{"No audience", oidcConfig{Issuer: testIssuer, Algorithms: []validator.SignatureAlgorithm{validator.RS256}}},
+		{"Unknown algorithm", oidcConfig{Issuer: testIssuer, Audience: []string{"dump.link"}, Algorithms: []validator.SignatureAlgorithm{"none"}}},
+		{"Missing JWKS file", oidcConfig{Issuer: testIssuer, Audience: []string{"dump.link"}, Algorithms: []validator.SignatureAlgorithm{validator.RS256}, JWKSFile: "/does/not/exist.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTokenVerifier(tt.cfg)
+			if err == nil {
+				t.Error("newTokenVerifier() accepted an invalid config")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"dump.link/src/models"
+)
+
+// presence is a user looking at the board of a project, no matter in how
+// many tabs.
+type presence struct {
+	Username string `json:"username"`
+	// Activity is the bucket or task the user works on, if any.
+	Activity *models.Activity `json:"activity"`
+}
+
+// projectRoster returns everybody connected to the project, once per
+// username. Clients without a username are left out.
+func (app *application) projectRoster(ctx context.Context, projectId string) ([]presence, error) {
+	usernames := app.hub.usernames(projectId)
+
+	activities, err := app.projectActivities(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := make([]presence, 0, len(usernames))
+	for _, username := range usernames {
+		roster = append(roster, presence{
+			Username: username,
+			Activity: activityOf(activities, username),
+		})
+	}
+
+	return roster, nil
+}
+
+// userPresence returns the presence of a single user of the project.
+func (app *application) userPresence(ctx context.Context, projectId string, username string) presence {
+	p := presence{Username: username}
+
+	activities, err := app.projectActivities(ctx, projectId)
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error loading activities for presence: %v", err))
+		return p
+	}
+
+	p.Activity = activityOf(activities, username)
+	return p
+}
+
+func activityOf(activities []*models.Activity, username string) *models.Activity {
+	for _, activity := range activities {
+		if activity.CreatedBy == username {
+			return activity
+		}
+	}
+	return nil
+}
+
+// sendRoster tells a new client who else is on the board.
+func (app *application) sendRoster(ctx context.Context, client *wsClient) {
+	roster, err := app.projectRoster(ctx, client.projectId)
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error building the presence roster: %v", err))
+		return
+	}
+
+	messageJSON, err := json.Marshal(wsEnvelope{Action: ActionPresenceRoster, Data: roster})
+	if err != nil {
+		app.logger.Info(fmt.Sprintf("Error marshalling WebSocket data: %v", err))
+		return
+	}
+
+	app.hub.send(client, messageJSON)
+}
@@ -0,0 +1,22 @@
+package src
+
+import (
+	"testing"
+
+	"dump.link/src/models"
+)
+
+func TestActivityOf(t *testing.T) {
+	bucketId := "abcdefghijkmnopqrstuvw"
+	activities := []*models.Activity{
+		{CreatedBy: "Grace"},
+		{CreatedBy: "Ada", BucketID: &bucketId},
+	}
+
+	if got := activityOf(activities, "Ada"); got == nil || got.BucketID != &bucketId {
+		t.Errorf("activityOf(Ada) = %v, want the bucket activity", got)
+	}
+	if got := activityOf(activities, "Linus"); got != nil {
+		t.Errorf("activityOf(Linus) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,289 @@
+package src
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routeClass groups routes that share a rate limit budget.
+type routeClass string
+
+const (
+	routeClassRead          routeClass = "read"
+	routeClassMutation      routeClass = "mutation"
+	routeClassProjectCreate routeClass = "project_create"
+	routeClassWebsocket     routeClass = "websocket"
+)
+
+var routeClasses = []routeClass{routeClassRead, routeClassMutation, routeClassProjectCreate, routeClassWebsocket}
+
+// rateLimit is a token bucket refilled with Rate tokens per second, holding at
+// most Burst tokens. A zero Rate disables the limit.
+type rateLimit struct {
+	Rate  float64
+	Burst float64
+}
+
+// rateLimitConfig has one budget per client IP and one per project for every
+// route class. Project creation has no project budget.
+type rateLimitConfig struct {
+	Enabled bool
+	// IPHeader is trusted for the client IP instead of the remote address,
+	// e.g. Fly-Client-IP behind the fly.io proxy.
+	IPHeader string
+
+	PerIP      map[routeClass]rateLimit
+	PerProject map[routeClass]rateLimit
+}
+
+func defaultRateLimitConfig() rateLimitConfig {
+	return rateLimitConfig{
+		Enabled: true,
+		PerIP: map[routeClass]rateLimit{
+			routeClassRead:          {Rate: 20, Burst: 40},
+			routeClassMutation:      {Rate: 10, Burst: 30},
+			routeClassProjectCreate: {Rate: 1.0 / 60, Burst: 5},
+			routeClassWebsocket:     {Rate: 1, Burst: 10},
+		},
+		PerProject: map[routeClass]rateLimit{
+			routeClassRead:      {Rate: 50, Burst: 100},
+			routeClassMutation:  {Rate: 30, Burst: 60},
+			routeClassWebsocket: {Rate: 5, Burst: 30},
+		},
+	}
+}
+
+// rateLimitConfigFromEnv starts from the defaults. Every budget can be
+// overridden with RATE_LIMIT_IP_<CLASS> and RATE_LIMIT_PROJECT_<CLASS>, e.g.
+// RATE_LIMIT_IP_MUTATION=10/s:30 or RATE_LIMIT_IP_PROJECT_CREATE=1/m:5.
+func rateLimitConfigFromEnv(getenv func(string) string) (rateLimitConfig, error) {
+	cfg := defaultRateLimitConfig()
+	cfg.IPHeader = getenv("RATE_LIMIT_IP_HEADER")
+
+	if enabled := getenv("RATE_LIMIT_ENABLED"); enabled != "" {
+		var err error
+		cfg.Enabled, err = strconv.ParseBool(enabled)
+		if err != nil {
+			return cfg, fmt.Errorf("RATE_LIMIT_ENABLED: %w", err)
+		}
+	}
+
+	for _, class := range routeClasses {
+		for prefix, limits := range map[string]map[routeClass]rateLimit{"RATE_LIMIT_IP_": cfg.PerIP, "RATE_LIMIT_PROJECT_": cfg.PerProject} {
+			name := prefix + strings.ToUpper(string(class))
+			value := getenv(name)
+			if value == "" {
+				continue
+			}
+
+			limit, err := parseRateLimit(value)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %w", name, err)
+			}
+			limits[class] = limit
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseRateLimit reads "<count>/<s|m|h>:<burst>". The burst defaults to the
+// count, "0" disables the limit.
+func parseRateLimit(value string) (rateLimit, error) {
+	if value == "0" {
+		return rateLimit{}, nil
+	}
+
+	spec, burstValue, hasBurst := strings.Cut(value, ":")
+	countValue, unit, found := strings.Cut(spec, "/")
+	if !found {
+		return rateLimit{}, fmt.Errorf("invalid rate limit %q, expected e.g. 10/s:20", value)
+	}
+
+	count, err := strconv.ParseFloat(countValue, 64)
+	if err != nil || count <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid count in rate limit %q", value)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return rateLimit{}, fmt.Errorf("invalid unit in rate limit %q, must be s, m or h", value)
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.ParseFloat(burstValue, 64)
+		if err != nil || burst < 1 {
+			return rateLimit{}, fmt.Errorf("invalid burst in rate limit %q", value)
+		}
+	}
+
+	return rateLimit{Rate: count / period.Seconds(), Burst: burst}, nil
+}
+
+// String formats the limit the way parseRateLimit reads it.
+func (l rateLimit) String() string {
+	if l.Rate == 0 {
+		return "0"
+	}
+
+	count, unit := l.Rate, "s"
+	if count < 1 {
+		count, unit = l.Rate*60, "m"
+	}
+	if count < 1 {
+		count, unit = l.Rate*3600, "h"
+	}
+
+	return fmt.Sprintf("%s/%s:%s", strconv.FormatFloat(count, 'f', -1, 64), unit, strconv.FormatFloat(l.Burst, 'f', -1, 64))
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// refill adds the tokens for the time passed since the bucket was last seen.
+// It returns how long until the bucket holds a whole token again.
+func (b *tokenBucket) refill(limit rateLimit, now time.Time) time.Duration {
+	b.tokens = math.Min(limit.Burst, b.tokens+now.Sub(b.lastSeen).Seconds()*limit.Rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+type rateLimiter struct {
+	config rateLimitConfig
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(cfg rateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		config:  cfg,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow checks the IP and the project budget of the class. Both are charged
+// only if both have a token left.
+func (l *rateLimiter) allow(class routeClass, ip string, projectId string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	type charge struct {
+		key   string
+		limit rateLimit
+	}
+	charges := []charge{{"ip:" + string(class) + ":" + ip, l.config.PerIP[class]}}
+	if projectId != "" {
+		charges = append(charges, charge{"project:" + string(class) + ":" + projectId, l.config.PerProject[class]})
+	}
+
+	var (
+		buckets    []*tokenBucket
+		retryAfter time.Duration
+	)
+	for _, c := range charges {
+		if c.limit.Rate == 0 {
+			continue
+		}
+
+		bucket, ok := l.buckets[c.key]
+		if !ok {
+			bucket = &tokenBucket{tokens: c.limit.Burst, lastSeen: now}
+			l.buckets[c.key] = bucket
+		}
+		buckets = append(buckets, bucket)
+
+		if wait := bucket.refill(c.limit, now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, 0
+}
+
+// cleanup forgets buckets that have been full for a while.
+func (l *rateLimiter) cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	cutoff := time.Now().Add(-10 * time.Minute)
+	for key, bucket := range l.buckets {
+		if bucket.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// classifyRoute returns the route class and, for project routes, the project
+// ID. Requests that are not rate limited, like static files, return false.
+func classifyRoute(r *http.Request) (routeClass, string, bool) {
+	path := r.URL.Path
+	if !strings.HasPrefix(path, "/api/") {
+		return "", "", false
+	}
+
+	// event streams count as websockets.
+	for _, prefix := range []string{"/api/v1/ws/", "/api/v1/sse/"} {
+		if rest, found := strings.CutPrefix(path, prefix); found {
+			projectId, _, _ := strings.Cut(rest, "/")
+			return routeClassWebsocket, projectId, true
+		}
+	}
+
+	var projectId string
+	if rest, found := strings.CutPrefix(path, "/api/v1/projects/"); found {
+		projectId, _, _ = strings.Cut(rest, "/")
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return routeClassRead, projectId, true
+	case http.MethodPost:
+		if path == "/api/v1/projects" {
+			return routeClassProjectCreate, "", true
+		}
+	}
+
+	return routeClassMutation, projectId, true
+}
+
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.config.IPHeader != "" {
+		if ip := r.Header.Get(l.config.IPHeader); ip != "" {
+			return ip
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,114 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    rateLimit
+		wantErr bool
+	}{
+		{"10/s:20", rateLimit{Rate: 10, Burst: 20}, false},
+		{"10/s", rateLimit{Rate: 10, Burst: 10}, false},
+		{"6/m:5", rateLimit{Rate: 0.1, Burst: 5}, false},
+		{"0", rateLimit{}, false},
+		{"10", rateLimit{}, true},
+		{"10/d", rateLimit{}, true},
+		{"-1/s", rateLimit{}, true},
+		{"10/s:0", rateLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRateLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRateLimit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitString(t *testing.T) {
+	for _, value := range []string{"10/s:20", "6/m:5", "1/h:3", "0"} {
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := limit.String(); got != value {
+			t.Errorf("parseRateLimit(%q).String() = %q", value, got)
+		}
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{
+		Enabled:    true,
+		PerIP:      map[routeClass]rateLimit{routeClassMutation: {Rate: 1, Burst: 2}},
+		PerProject: map[routeClass]rateLimit{routeClassMutation: {Rate: 1, Burst: 3}},
+	})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.allow(routeClassMutation, "1.1.1.1", "abc", now); !allowed {
+			t.Fatalf("request %d was rejected", i)
+		}
+	}
+
+	allowed, retryAfter := limiter.allow(routeClassMutation, "1.1.1.1", "abc", now)
+	if allowed || retryAfter != time.Second {
+		t.Fatalf("allow() = %v, %v, want false, 1s", allowed, retryAfter)
+	}
+
+	// the rejected request must not have used up the project budget.
+	if allowed, _ := limiter.allow(routeClassMutation, "2.2.2.2", "abc", now); !allowed {
+		t.Fatal("another IP was rejected")
+	}
+	if allowed, _ := limiter.allow(routeClassMutation, "3.3.3.3", "abc", now); allowed {
+		t.Fatal("the project budget was not enforced")
+	}
+
+	if allowed, _ := limiter.allow(routeClassMutation, "1.1.1.1", "abc", now.Add(time.Second)); !allowed {
+		t.Fatal("the bucket was not refilled")
+	}
+
+	if allowed, _ := limiter.allow(routeClassRead, "1.1.1.1", "abc", now); !allowed {
+		t.Fatal("a class without limits was rejected")
+	}
+}
+
+func TestClassifyRoute(t *testing.T) {
+	tests := []struct {
+		method      string
+		path        string
+		wantClass   routeClass
+		wantProject string
+		wantLimited bool
+	}{
+		{http.MethodGet, "/static/app/main.js", "", "", false},
+		{http.MethodGet, "/api/v1/projects/abc", routeClassRead, "abc", true},
+		{http.MethodPost, "/api/v1/projects", routeClassProjectCreate, "", true},
+		{http.MethodPost, "/api/v1/projects/abc/tasks", routeClassMutation, "abc", true},
+		{http.MethodDelete, "/api/v1/projects/abc/tasks/abcdef", routeClassMutation, "abc", true},
+		{http.MethodGet, "/api/v1/ws/abc", routeClassWebsocket, "abc", true},
+		{http.MethodGet, "/api/v1/sse/abc", routeClassWebsocket, "abc", true},
+		{http.MethodGet, "/api/v1/search", routeClassRead, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			class, projectId, limited := classifyRoute(r)
+			if class != tt.wantClass || projectId != tt.wantProject || limited != tt.wantLimited {
+				t.Errorf("classifyRoute() = %q, %q, %v, want %q, %q, %v", class, projectId, limited, tt.wantClass, tt.wantProject, tt.wantLimited)
+			}
+		})
+	}
+}
@@ -1,8 +1,17 @@
 package src
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	errRecordNotFound = errors.New("record not found")
+	errNoUpdates      = errors.New("no updates provided")
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -11,11 +20,14 @@ func (app *application) logError(r *http.Request, err error) {
 		uri    = r.URL.RequestURI()
 	)
 
-	app.logger.Error(err.Error(), "method", method, "uri", uri)
+	app.logger.ErrorContext(r.Context(), err.Error(), "method", method, "uri", uri)
 }
 
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
+	if id := requestIDFromContext(r.Context()); id != "" {
+		env["request_id"] = id
+	}
 
 	err := app.writeJSON(w, status, env, nil)
 	if err != nil {
@@ -51,7 +63,32 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
 func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "Unauthorized: Access is denied due to missing Username."
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
+
+func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you do not have the permission to perform this action"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) invalidShareTokenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the share link is invalid, expired or was revoked"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
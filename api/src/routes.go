@@ -3,12 +3,11 @@ package src
 import (
 	"net/http"
 
+	"dump.link/src/models"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 )
 
-var allowedOrigins = []string{"http://localhost:1234", "http://localhost:8080", "https://kitchen.dump.link", "https://dump.link"}
-
 func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
@@ -18,36 +17,95 @@ func (app *application) routes() http.Handler {
 	fileServer := http.FileServer(http.Dir("./static/"))
 	router.Handler(http.MethodGet, "/static/*filepath", http.StripPrefix("/static", fileServer))
 
+	router.HandlerFunc(http.MethodGet, "/api/v1/health", app.HealthGet)
+	router.HandlerFunc(http.MethodGet, "/api/v1/ready", app.ReadyGet)
+
 	router.HandlerFunc(http.MethodGet, "/", app.RootGet)
 	router.HandlerFunc(http.MethodGet, "/a", app.ProjectRoot)
 
 	router.HandlerFunc(http.MethodGet, "/a/:projectId", app.ProjectGet)
 	// /a/dashboard
 	router.HandlerFunc(http.MethodGet, "/a/:projectId/*any", app.ProjectGet)
-	router.HandlerFunc(http.MethodGet, "/api/v1/private", EnsureValidToken(app.PrivateGet))
+	router.HandlerFunc(http.MethodGet, "/api/v1/private", app.EnsureValidToken(app.PrivateGet))
+	router.HandlerFunc(http.MethodGet, "/api/v1/search", app.EnsureValidToken(app.ApiSearch))
+	router.HandlerFunc(http.MethodGet, "/api/v1/dashboard", app.EnsureValidToken(app.ApiGetDashboard))
+
+	// every project route states the role it needs. Share links only open
+	// the shared routes.
+	shared := func(next http.HandlerFunc) http.HandlerFunc {
+		return app.requireProjectRole(models.RoleViewer, true, next)
+	}
+	viewer := func(next http.HandlerFunc) http.HandlerFunc {
+		return app.requireProjectRole(models.RoleViewer, false, next)
+	}
+	editor := func(next http.HandlerFunc) http.HandlerFunc {
+		return app.requireProjectRole(models.RoleEditor, false, next)
+	}
+	owner := func(next http.HandlerFunc) http.HandlerFunc {
+		return app.requireProjectRole(models.RoleOwner, false, next)
+	}
+
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects", app.OptionalValidToken(app.ApiProjectsPost))
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId", shared(app.ApiProjectGet))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId", editor(app.ApiProjectPatch))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/resetLayers", editor(app.ApiResetProjectLayers))
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/export", viewer(app.ApiProjectExport))
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/search", viewer(app.ApiProjectSearch))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/members", viewer(app.ApiGetMembers))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/members", owner(app.ApiPostMember))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/members/:subject", owner(app.ApiDeleteMember))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/transfer", editor(app.ApiTransferOwnership))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/shares", owner(app.ApiGetShareTokens))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/shares", owner(app.ApiPostShareToken))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/shares/:shareId", owner(app.ApiDeleteShareToken))
 
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects", app.ApiProjectsPost)
-	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId", app.ApiProjectGet)
-	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId", app.ApiProjectPatch)
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/resetLayers", app.ApiResetProjectLayers)
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/activities", editor(app.ApiActivityPost))
 
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/activities", app.ApiActivityPost)
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/tasks", editor(app.ApiPostTask))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/tasks/:taskId", editor(app.ApiDeleteTask))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId/tasks/:taskId", editor(app.ApiPatchTask))
 
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/tasks", app.ApiPostTask)
-	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/tasks/:taskId", app.ApiDeleteTask)
-	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId/tasks/:taskId", app.ApiPatchTask)
+	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId/buckets/:bucketId", editor(app.ApiPatchBucket))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/buckets/:bucketId/resetLayers", editor(app.ApiResetBucketLayers))
 
-	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId/buckets/:bucketId", app.ApiPatchBucket)
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/buckets/:bucketId/resetLayers", app.ApiResetBucketLayers)
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/dependencies", editor(app.ApiAddDependency))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", editor(app.ApiRemoveDependency))
 
-	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/dependencies", app.ApiAddDependency)
-	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/dependencies/:bucketId/:dependencyId", app.ApiRemoveDependency)
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/comments", viewer(app.ApiGetComments))
+	router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/comments", editor(app.ApiPostComment))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/projects/:projectId/comments/:commentId", editor(app.ApiPatchComment))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/comments/:commentId", editor(app.ApiDeleteComment))
 
-	router.HandlerFunc(http.MethodGet, "/api/v1/ws/:projectId", app.adaptHandler(app.apiHandleWebSocket))
+	// the webhook URLs are secrets that let anybody post to the team's chat.
+	if app.config.Features.Notifications {
+		router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId/notifications", owner(app.ApiGetNotificationTargets))
+		router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/notifications", owner(app.ApiPostNotificationTarget))
+		router.HandlerFunc(http.MethodDelete, "/api/v1/projects/:projectId/notifications/:targetId", owner(app.ApiDeleteNotificationTarget))
+	}
+
+	if app.config.Features.Github {
+		// GitHub signs its requests with the project's secret instead.
+		router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/github", app.ApiGithubWebhook)
+		router.HandlerFunc(http.MethodPost, "/api/v1/projects/:projectId/github/secret", owner(app.ApiGithubSecretPost))
+	}
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/users/:username/tasks", app.EnsureValidToken(app.ApiGetAssignedTasks))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/ws/:projectId", shared(app.adaptHandler(app.apiHandleWebSocket)))
+	if app.config.Features.SSE {
+		router.HandlerFunc(http.MethodGet, "/api/v1/sse/:projectId", shared(app.ApiProjectEvents))
+	}
+
+	// without a listener of their own, the metrics need a token.
+	if app.config.Metrics.Addr == "" && app.config.Metrics.Token != "" {
+		router.Handler(http.MethodGet, "/metrics", app.metrics.handler(app.config.Metrics.Token))
+	}
 
-	standard := alice.New(app.recoverPanic, app.enableCORS, app.logRequest, app.measureResponseTime, secureHeaders)
+	standard := alice.New(app.requestID, app.recoverPanic, app.enableCORS, app.logRequest, app.rateLimit, app.measureResponseTime(router), app.secureHeaders)
 
-	return standard.Then(router)
+	return traceRequests(router, standard.Then(traceHandler(router)))
 }
 
 func (app *application) adaptHandler(h httprouter.Handle) http.HandlerFunc {
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jobStatus is what the readiness check knows about a scheduled job.
+type jobStatus struct {
+	interval time.Duration
+	// lastRun is when the job last finished without panicking, or when it
+	// was scheduled.
+	lastRun time.Time
+}
+
+// stale reports whether the job missed several runs, e.g. because it hangs
+// or keeps panicking.
+func (s jobStatus) stale(now time.Time) bool {
+	limit := 3 * s.interval
+	if limit < time.Minute {
+		limit = time.Minute
+	}
+	return now.Sub(s.lastRun) > limit
+}
+
+// scheduler keeps the status of the scheduled jobs.
+type scheduler struct {
+	mutex sync.Mutex
+	jobs  map[string]*jobStatus
+}
+
+func (s *scheduler) add(name string, interval time.Duration, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.jobs == nil {
+		s.jobs = make(map[string]*jobStatus)
+	}
+	s.jobs[name] = &jobStatus{interval: interval, lastRun: now}
+}
+
+func (s *scheduler) ran(name string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs[name].lastRun = now
+}
+
+// staleJobs returns the names of the stale jobs, sorted.
+func (s *scheduler) staleJobs(now time.Time) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stale := []string{}
+	for name, job := range s.jobs {
+		if job.stale(now) {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// schedule runs the job every interval until the process exits. A panicking
+// run is logged and does not count as a run.
+func (app *application) schedule(name string, interval time.Duration, job func()) {
+	app.scheduler.add(name, interval, time.Now())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.logger.Debug("running scheduled job", "job", name)
+			if app.runJob(name, job) {
+				app.scheduler.ran(name, time.Now())
+			}
+		}
+	}()
+}
+
+func (app *application) runJob(name string, job func()) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error("scheduled job panicked", "job", name, "error", fmt.Sprintf("%v", err))
+		}
+	}()
+
+	job()
+	return true
+}
+
+// background runs the function in a goroutine that shutdown waits for.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
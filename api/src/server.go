@@ -1,108 +1,299 @@
 package src
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	"dump.link/src/models"
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/gorilla/websocket"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type wsClient struct {
-	conn           *websocket.Conn
-	projectId      string
-	clientToken    string
-	clientUsername string
-}
 type application struct {
+	config      config
 	templatesFS embed.FS
 
-	logger *slog.Logger
+	logger  *slog.Logger
+	metrics *metrics
+
+	// db is only used directly by the readiness check.
+	db *sql.DB
+
+	activities          *models.ActivityModel
+	buckets             *models.BucketModel
+	tasks               *models.TaskModel
+	projects            *models.ProjectModel
+	dependencies        *models.DependencyModel
+	notificationTargets *models.NotificationTargetModel
+	taskLinks           *models.TaskLinkModel
+	comments            *models.CommentModel
+	taskAssignees       *models.TaskAssigneeModel
+	search              *models.SearchModel
+	members             *models.ProjectMemberModel
+	shareTokens         *models.ShareTokenModel
+	dashboard           *models.DashboardModel
+	projectVisits       *models.ProjectVisitModel
+	actions             *models.LogActionModel
+	logSubscriptions    *models.LogSubscriptionModel
+	brokerMessages      *models.BrokerMessageModel
+	migrations          *models.MigrationModel
+
+	scheduler scheduler
 
-	activities       *models.ActivityModel
-	buckets          *models.BucketModel
-	tasks            *models.TaskModel
-	projects         *models.ProjectModel
-	dependencies     *models.DependencyModel
-	actions          *models.LogActionModel
-	logSubscriptions *models.LogSubscriptionModel
+	auth          *authenticator
+	limiter       *rateLimiter
+	webhookClient *http.Client
 
-	clients map[string]map[*wsClient]bool // Map projectId to Clients
-	mutex   sync.Mutex
+	liveActivities *activityTracker
+
+	hub    *hub
+	broker broker
+
+	// wg tracks websocket handlers and background tasks for shutdown.
+	wg sync.WaitGroup
 }
 
 func Run(templatesFS embed.FS) error {
-	addr := flag.String("addr", "0.0.0.0:8080", "HTTP network address")
-	flag.Parse()
-	logLevel := slog.LevelInfo
-	env := os.Getenv("ENV")
-	if env == "" {
-		logLevel = slog.LevelDebug
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
 	}
-	opts := &slog.HandlerOptions{
-		Level: logLevel,
+	if err != nil {
+		return err
 	}
-	// Use the slog.New() function to initialize a new structured logger, which
-	// writes to the standard out stream and uses the default settings.
-	logger := slog.New(slog.NewTextHandler(os.Stdout, opts))
 
-	db, err := openDB()
+	logger := newLogger(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	// the models and main log through the default logger.
+	slog.SetDefault(logger)
+
+	logger.Info("effective config", cfg.logAttrs()...)
+
+	shutdownTracing, err := setupTracing(cfg.Tracing, cfg.Env)
 	if err != nil {
-		logger.Error(err.Error())
+		return err
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
+		err := shutdownTracing(ctx)
+		if err != nil {
+			logger.Error("flushing spans", "error", err)
+		}
+	}()
+
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return err
+	}
 	defer db.Close()
 
+	metrics := newMetrics()
+	metrics.registerDB(db)
+
 	app := &application{
+		config:      cfg,
 		templatesFS: templatesFS,
 		logger:      logger,
+		metrics:     metrics,
+		db:          db,
+
+		activities:          &models.ActivityModel{DB: db},
+		buckets:             &models.BucketModel{DB: db},
+		tasks:               &models.TaskModel{DB: db},
+		projects:            &models.ProjectModel{DB: db},
+		dependencies:        &models.DependencyModel{DB: db},
+		notificationTargets: &models.NotificationTargetModel{DB: db},
+		taskLinks:           &models.TaskLinkModel{DB: db},
+		comments:            &models.CommentModel{DB: db},
+		taskAssignees:       &models.TaskAssigneeModel{DB: db},
+		search:              &models.SearchModel{DB: db},
+		members:             &models.ProjectMemberModel{DB: db},
+		shareTokens:         &models.ShareTokenModel{DB: db},
+		dashboard:           &models.DashboardModel{DB: db},
+		projectVisits:       &models.ProjectVisitModel{DB: db},
+		actions:             &models.LogActionModel{DB: db},
+		logSubscriptions:    &models.LogSubscriptionModel{DB: db},
+		brokerMessages:      &models.BrokerMessageModel{DB: db},
+		migrations:          &models.MigrationModel{DB: db},
 
-		activities:       &models.ActivityModel{DB: db},
-		buckets:          &models.BucketModel{DB: db},
-		tasks:            &models.TaskModel{DB: db},
-		projects:         &models.ProjectModel{DB: db},
-		dependencies:     &models.DependencyModel{DB: db},
-		actions:          &models.LogActionModel{DB: db},
-		logSubscriptions: &models.LogSubscriptionModel{DB: db},
+		webhookClient: newWebhookClient(cfg.AllowPrivateWebhooks),
 
-		clients: make(map[string]map[*wsClient]bool),
+		hub: newHub(logger, metrics),
 	}
 
-	logger.Info(fmt.Sprintf("starting server at http://%s", *addr))
-	err = http.ListenAndServe(*addr, app.routes())
-	logger.Error(err.Error())
+	app.auth, err = app.newAuthenticator(cfg.OIDC)
 	if err != nil {
-		logger.Error(err.Error())
-		os.Exit(1) // This will exit the program if there's an error starting the server
+		return err
 	}
 
+	app.liveActivities = newActivityTracker(cfg.ActivityTTL)
+	app.limiter = newRateLimiter(cfg.RateLimit)
+
+	app.broker, err = app.newBroker(cfg.Broker)
+	if err != nil {
+		return err
+	}
+
+	app.schedule("rate limiter cleanup", time.Minute, app.limiter.cleanup)
+	app.schedule("hub cleanup", time.Minute, app.hub.cleanup)
+	app.schedule("activity expiry", time.Minute, app.expireActivities)
+	if cfg.Features.Notifications {
+		app.schedule("appetite notifications", 5*time.Minute, app.checkAppetites)
+	}
+
+	return app.serve()
+}
+
+// serve runs the server until SIGINT or SIGTERM. It then tells websockets to
+// reconnect and waits, at most the shutdown timeout, for in-flight requests,
+// websocket handlers and background tasks.
+func (app *application) serve() error {
+	cfg := app.config.Server
+
+	srv := &http.Server{
+		Addr:         app.config.Addr,
+		Handler:      app.routes(),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	var metricsSrv *http.Server
+	if app.config.Metrics.Addr != "" {
+		metricsSrv = app.metricsServer()
+	}
+
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Info("shutting down server", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		// Shutdown does not track websockets and waits for event streams, so
+		// close them first.
+		app.hub.shutdown()
+
+		if metricsSrv != nil {
+			metricsSrv.Shutdown(ctx)
+		}
+
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Info("waiting for websockets and background tasks")
+
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- fmt.Errorf("waiting for websockets and background tasks: %w", ctx.Err())
+		}
+	}()
+
+	app.logger.Info(fmt.Sprintf("starting server at http://%s", app.config.Addr))
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server")
 	return nil
 }
 
-// The openDB() function wraps sql.Open() and returns a sql.DB connection pool
-// for a given DSN.
-func openDB() (*sql.DB, error) {
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASS")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbTls := os.Getenv("DB_TLS")
+// metricsServer serves /metrics on the metrics address in the background.
+func (app *application) metricsServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", app.metrics.handler(app.config.Metrics.Token))
+
+	srv := &http.Server{
+		Addr:         app.config.Metrics.Addr,
+		Handler:      mux,
+		ReadTimeout:  app.config.Server.ReadTimeout,
+		WriteTimeout: app.config.Server.WriteTimeout,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?tls=%s&interpolateParams=true", user, password, dbHost, dbName, dbTls)
+	go func() {
+		app.logger.Info(fmt.Sprintf("serving metrics at http://%s/metrics", srv.Addr))
+
+		err := srv.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Error("metrics server stopped", "error", err)
+		}
+	}()
+
+	return srv
+}
 
-	db, err := sql.Open("mysql", dsn)
+// The openDB() function wraps sql.Open() and returns a sql.DB connection pool
+// for the configured database. The server does not start without one, so an
+// unreachable database fails after the connect timeout.
+func openDB(cfg dbConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?tls=%s&interpolateParams=true&timeout=%s", cfg.User, cfg.Password, cfg.Host, cfg.Name, cfg.TLS, cfg.ConnectTimeout)
+
+	// queries get a span when they run within a traced request.
+	db, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(semconv.DBSystemMySQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
-	if err = db.Ping(); err != nil {
-		return nil, err
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database %s unreachable: %w", cfg.Host, err)
 	}
 	return db, nil
 }
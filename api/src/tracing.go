@@ -0,0 +1,134 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the application. Until a tracer provider is
+// set up, it creates no-op spans.
+var tracer = otel.Tracer("dump.link")
+
+// tracingConfig selects where spans go with TRACING_EXPORTER: "none", "stdout"
+// for local runs or "otlp" to send them to TRACING_ENDPOINT over HTTP.
+type tracingConfig struct {
+	Exporter string
+	Endpoint string
+	// SampleRatio is the share of new traces that are recorded. Requests
+	// continuing a sampled trace are always recorded.
+	SampleRatio float64
+}
+
+func tracingConfigFromEnv(getenv func(string) string) (tracingConfig, error) {
+	cfg := tracingConfig{
+		Exporter:    "none",
+		Endpoint:    "http://localhost:4318",
+		SampleRatio: 1,
+	}
+
+	if exporter := getenv("TRACING_EXPORTER"); exporter != "" {
+		cfg.Exporter = exporter
+	}
+	if cfg.Exporter != "none" && cfg.Exporter != "stdout" && cfg.Exporter != "otlp" {
+		return cfg, fmt.Errorf("TRACING_EXPORTER: unknown exporter %q, expected none, stdout or otlp", cfg.Exporter)
+	}
+
+	if endpoint := getenv("TRACING_ENDPOINT"); endpoint != "" {
+		cfg.Endpoint = endpoint
+	}
+
+	if ratio := getenv("TRACING_SAMPLE_RATIO"); ratio != "" {
+		var err error
+		cfg.SampleRatio, err = strconv.ParseFloat(ratio, 64)
+		if err != nil || cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+			return cfg, fmt.Errorf("TRACING_SAMPLE_RATIO: must be between 0 and 1, got %q", ratio)
+		}
+	}
+
+	return cfg, nil
+}
+
+// setupTracing installs the global tracer provider. The returned function
+// flushes the remaining spans on shutdown.
+func setupTracing(cfg tracingConfig, env string) (func(context.Context) error, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch cfg.Exporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("dump.link"),
+		semconv.DeploymentEnvironment(env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// traceRequests starts the span of every request, named after its route,
+// around the whole middleware chain.
+func traceRequests(router *httprouter.Router, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeLabel(router, r)
+		}),
+	)
+}
+
+// traceHandler adds a span for the handler itself, so that the time spent in
+// the middleware stands out.
+func traceHandler(router *httprouter.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeLabel(router, r)
+
+		ctx, span := tracer.Start(r.Context(), "handler "+route,
+			trace.WithAttributes(semconv.HTTPRoute(route)),
+		)
+		defer span.End()
+
+		router.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// projectAttr tags spans with the project they work on.
+func projectAttr(projectId string) attribute.KeyValue {
+	return attribute.String("project.id", projectId)
+}
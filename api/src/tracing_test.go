@@ -0,0 +1,125 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spanRecorder     *tracetest.SpanRecorder
+	spanRecorderOnce sync.Once
+)
+
+// recordSpans installs a tracer provider recording all spans. The global
+// provider can only be replaced once for the tracer of the package, so all
+// tests share the recorder.
+func recordSpans() *tracetest.SpanRecorder {
+	spanRecorderOnce.Do(func() {
+		spanRecorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	})
+	return spanRecorder
+}
+
+func endedSpan(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracingConfigFromEnv(t *testing.T) {
+	cfg, err := tracingConfigFromEnv(mapGetenv(map[string]string{
+		"TRACING_EXPORTER":     "otlp",
+		"TRACING_ENDPOINT":     "http://collector:4318",
+		"TRACING_SAMPLE_RATIO": "0.25",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Exporter != "otlp" || cfg.Endpoint != "http://collector:4318" || cfg.SampleRatio != 0.25 {
+		t.Errorf("tracingConfigFromEnv() = %+v", cfg)
+	}
+
+	for _, env := range []map[string]string{
+		{"TRACING_EXPORTER": "jaeger"},
+		{"TRACING_SAMPLE_RATIO": "2"},
+	} {
+		_, err := tracingConfigFromEnv(mapGetenv(env))
+		if err == nil {
+			t.Errorf("tracingConfigFromEnv(%v) succeeded, want an error", env)
+		}
+	}
+}
+
+func TestTraceHandler(t *testing.T) {
+	recorder := recordSpans()
+
+	var buf bytes.Buffer
+	logger := slog.New(contextLogHandler{slog.NewTextHandler(&buf, nil)})
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/api/v1/projects/:projectId", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "handling")
+	})
+
+	handler := traceRequests(router, traceHandler(router))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/projects/abc", nil))
+
+	request := endedSpan(recorder, "GET /api/v1/projects/:projectId")
+	if request == nil {
+		t.Fatal("no span for the request")
+	}
+	span := endedSpan(recorder, "handler /api/v1/projects/:projectId")
+	if span == nil {
+		t.Fatal("no span for the handler")
+	}
+	if span.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("handler span is not a child of the request span")
+	}
+
+	if want := "trace_id=" + span.SpanContext().TraceID().String(); !strings.Contains(buf.String(), want) {
+		t.Errorf("log %q does not contain %s", buf.String(), want)
+	}
+}
+
+func TestHubBroadcastSpan(t *testing.T) {
+	recorder := recordSpans()
+
+	h := newHub(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	h.register(newTestClient("traced", "t1", "Ada", 1))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	h.broadcast(ctx, "traced", "", []byte("hello"))
+	parent.End()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "hub.broadcast" && s.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+			span = s
+		}
+	}
+	if span == nil {
+		t.Fatal("no broadcast span in the trace of the request")
+	}
+
+	for _, attr := range span.Attributes() {
+		if attr.Key == "clients" && attr.Value.AsInt64() != 1 {
+			t.Errorf("clients = %d, want 1", attr.Value.AsInt64())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package validation collects field errors of request input, so that clients
+// get all of them at once instead of one per request.
+package validation
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ProjectIDLength is the length of project IDs.
+	ProjectIDLength = 11
+	// EntityIDLength is the length of bucket, task and other IDs, which are
+	// prefixed with the ID of their project.
+	EntityIDLength = 22
+
+	// MaxNameLength fits the VARCHAR(255) columns for names and titles.
+	MaxNameLength = 255
+)
+
+var base58RX = regexp.MustCompile("^[1-9A-HJ-NP-Za-km-z]+$")
+
+// Validator holds the errors by field name.
+type Validator struct {
+	Errors map[string]string
+}
+
+func New() *Validator {
+	return &Validator{Errors: make(map[string]string)}
+}
+
+// Valid reports whether no errors were added.
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError adds an error for the field, unless the field already has one.
+func (v *Validator) AddError(key, message string) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = message
+	}
+}
+
+// Check adds the error if ok is false.
+func (v *Validator) Check(ok bool, key, message string) {
+	if !ok {
+		v.AddError(key, message)
+	}
+}
+
+// NotBlank reports whether the value contains anything but whitespace.
+func NotBlank(value string) bool {
+	return strings.TrimSpace(value) != ""
+}
+
+// MaxChars reports whether the value has at most n characters.
+func MaxChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) <= n
+}
+
+// Between reports whether min <= value <= max.
+func Between(value, min, max int) bool {
+	return value >= min && value <= max
+}
+
+// PermittedValue reports whether the value is one of the permitted values.
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	for _, permitted := range permittedValues {
+		if value == permitted {
+			return true
+		}
+	}
+	return false
+}
+
+// IsID reports whether the value is a base58 ID of the given length.
+func IsID(value string, length int) bool {
+	return len(value) == length && base58RX.MatchString(value)
+}
+
+// InProject reports whether the value is an entity ID of the project. The
+// first 11 characters of every entity ID are its project's ID.
+func InProject(value string, projectId string) bool {
+	return IsID(value, EntityIDLength) && strings.HasPrefix(value, projectId)
+}
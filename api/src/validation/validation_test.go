@@ -0,0 +1,58 @@
+package validation
+
+import "testing"
+
+func TestValidator(t *testing.T) {
+	v := New()
+	v.Check(true, "title", "must be provided")
+	v.Check(false, "bucketId", "must be a valid ID")
+	v.Check(false, "bucketId", "must exist")
+
+	if v.Valid() {
+		t.Fatal("Valid() = true, want false")
+	}
+	if len(v.Errors) != 1 || v.Errors["bucketId"] != "must be a valid ID" {
+		t.Errorf("Errors = %v, want only the first error of bucketId", v.Errors)
+	}
+}
+
+func TestIsID(t *testing.T) {
+	tests := []struct {
+		value  string
+		length int
+		want   bool
+	}{
+		{"abcdefghijk", ProjectIDLength, true},
+		{"abcdefghijkmnopqrstuvw", EntityIDLength, true},
+		{"abcdefghij", ProjectIDLength, false},
+		{"abcdefghijkl", ProjectIDLength, false},
+		// 0, O, I and l are not part of base58.
+		{"abcdefghij0", ProjectIDLength, false},
+		{"abcdefghijO", ProjectIDLength, false},
+		{"abcdefghij ", ProjectIDLength, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsID(tt.value, tt.length); got != tt.want {
+			t.Errorf("IsID(%q, %d) = %v, want %v", tt.value, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestInProject(t *testing.T) {
+	if !InProject("abcdefghijkmnopqrstuvw", "abcdefghijk") {
+		t.Error("InProject() rejected an ID of the project")
+	}
+	if InProject("bbcdefghijkmnopqrstuvw", "abcdefghijk") {
+		t.Error("InProject() accepted an ID of another project")
+	}
+}
+
+func TestMaxChars(t *testing.T) {
+	if !MaxChars("ääää", 4) {
+		t.Error("MaxChars() counted bytes instead of characters")
+	}
+	if MaxChars("aaaaa", 4) {
+		t.Error("MaxChars() accepted a value that is too long")
+	}
+}